@@ -0,0 +1,219 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Edge is a mesh edge between two welded vertices
+type Edge [2]fauxgl.Vector
+
+// Analysis reports the topological problems of a mesh
+type Analysis struct {
+	Vertices    int // after welding
+	Triangles   int
+	Shells      int
+	Boundary    []Edge // edges with only one face: holes in the surface
+	NonManifold []Edge // edges shared by more than two faces
+	Winding     []Edge // edges two faces traverse the same way
+}
+
+// Watertight reports whether the mesh is closed, manifold and consistently
+// wound
+func (a *Analysis) Watertight() bool {
+	return len(a.Boundary) == 0 && len(a.NonManifold) == 0 && len(a.Winding) == 0
+}
+
+// Print writes a readable summary of the analysis
+func (a *Analysis) Print(w io.Writer) {
+	fmt.Fprintf(w, "vertices      %d\n", a.Vertices)
+	fmt.Fprintf(w, "triangles     %d\n", a.Triangles)
+	fmt.Fprintf(w, "shells        %d\n", a.Shells)
+	fmt.Fprintf(w, "boundary      %d\n", len(a.Boundary))
+	fmt.Fprintf(w, "non-manifold  %d\n", len(a.NonManifold))
+	fmt.Fprintf(w, "bad winding   %d\n", len(a.Winding))
+	if a.Watertight() {
+		fmt.Fprintln(w, "watertight")
+	} else {
+		fmt.Fprintln(w, "NOT watertight")
+	}
+}
+
+// Weld merges each vertex of a de-indexed triangle buffer into one already
+// kept within tolerance of it, returning the unique vertices and three
+// indices per triangle
+func Weld(buffer []float32, tolerance float64) ([]fauxgl.Vector, []uint32) {
+	type key [3]int64
+	if tolerance <= 0 {
+		tolerance = 1e-9
+	}
+	// vertices are kept in a grid of cells four times tolerance wide,
+	// chained through next, so any within tolerance of a vertex are in its
+	// own cell or those across the sides it is within tolerance of
+	const none = ^uint32(0)
+	size := 4 * tolerance
+	lookup := make(map[key]uint32)
+	var vertices []fauxgl.Vector
+	var next []uint32
+	indices := make([]uint32, len(buffer)/3)
+	for i := range indices {
+		v := fauxgl.V(float64(buffer[i*3]), float64(buffer[i*3+1]), float64(buffer[i*3+2]))
+		var k, step key
+		for a, x := range [3]float64{v.X, v.Y, v.Z} {
+			cell := math.Floor(x / size)
+			k[a] = int64(cell)
+			if x-cell*size <= tolerance {
+				step[a] = -1
+			} else if (cell+1)*size-x <= tolerance {
+				step[a] = 1
+			}
+		}
+		index := none
+	search:
+		for n := 0; n < 8; n++ {
+			c := k
+			for a := 0; a < 3; a++ {
+				if n>>a&1 == 1 {
+					if step[a] == 0 {
+						continue search
+					}
+					c[a] += step[a]
+				}
+			}
+			j, ok := lookup[c]
+			for ok && j != none {
+				if vertices[j].Distance(v) <= tolerance {
+					index = j
+					break search
+				}
+				j = next[j]
+			}
+		}
+		if index == none {
+			index = uint32(len(vertices))
+			head, ok := lookup[k]
+			if !ok {
+				head = none
+			}
+			lookup[k] = index
+			vertices = append(vertices, v)
+			next = append(next, head)
+		}
+		indices[i] = index
+	}
+	return vertices, indices
+}
+
+// WeldTolerance returns a welding tolerance suited to a mesh within box
+func WeldTolerance(box fauxgl.Box) float64 {
+	return box.Size().Length() * 1e-6
+}
+
+// edgeKey orders an edge's vertex indices so both directions match
+func edgeKey(a, b uint32) [2]uint32 {
+	if a < b {
+		return [2]uint32{a, b}
+	}
+	return [2]uint32{b, a}
+}
+
+// edgeUse counts the faces on an edge and the net direction they traverse
+// it in; two consistently wound faces cancel out
+type edgeUse struct {
+	Faces     int
+	Direction int
+}
+
+// Analyze welds the vertices of data within tolerance and reports its
+// boundary, non-manifold and inconsistently wound edges and its shells
+func Analyze(data *MeshData, tolerance float64) *Analysis {
+	vertices, indices := Weld(data.Buffer, tolerance)
+	a := Analysis{Vertices: len(vertices), Triangles: len(indices) / 3}
+
+	edges := make(map[[2]uint32]*edgeUse)
+	for i := 0; i+2 < len(indices); i += 3 {
+		t := indices[i : i+3]
+		if t[0] == t[1] || t[1] == t[2] || t[2] == t[0] {
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			p, q := t[j], t[(j+1)%3]
+			k := edgeKey(p, q)
+			e, ok := edges[k]
+			if !ok {
+				e = &edgeUse{}
+				edges[k] = e
+			}
+			e.Faces++
+			if p < q {
+				e.Direction++
+			} else {
+				e.Direction--
+			}
+		}
+	}
+	for k, e := range edges {
+		edge := Edge{vertices[k[0]], vertices[k[1]]}
+		switch {
+		case e.Faces == 1:
+			a.Boundary = append(a.Boundary, edge)
+		case e.Faces > 2:
+			a.NonManifold = append(a.NonManifold, edge)
+		case e.Direction != 0:
+			a.Winding = append(a.Winding, edge)
+		}
+	}
+	a.Shells = countLabels(ComponentLabels(indices, len(vertices)))
+	return &a
+}
+
+// ComponentLabels labels each triangle of an indexed mesh with the
+// connected component it belongs to, numbered from zero
+func ComponentLabels(indices []uint32, vertexCount int) []int {
+	parent := make([]int, vertexCount)
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i := 0; i+2 < len(indices); i += 3 {
+		a := find(int(indices[i]))
+		for _, j := range indices[i+1 : i+3] {
+			b := find(int(j))
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+	labels := make([]int, len(indices)/3)
+	numbers := make(map[int]int)
+	for i := range labels {
+		root := find(int(indices[i*3]))
+		n, ok := numbers[root]
+		if !ok {
+			n = len(numbers)
+			numbers[root] = n
+		}
+		labels[i] = n
+	}
+	return labels
+}
+
+// countLabels returns the number of distinct labels
+func countLabels(labels []int) int {
+	n := 0
+	for _, l := range labels {
+		if l+1 > n {
+			n = l + 1
+		}
+	}
+	return n
+}
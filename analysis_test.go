@@ -0,0 +1,64 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// tetrahedron returns the outward wound faces of a unit tetrahedron
+func tetrahedron() []*fauxgl.Triangle {
+	a := fauxgl.V(0, 0, 0)
+	b := fauxgl.V(1, 0, 0)
+	c := fauxgl.V(0, 1, 0)
+	d := fauxgl.V(0, 0, 1)
+	return []*fauxgl.Triangle{
+		fauxgl.NewTriangleForPoints(a, c, b),
+		fauxgl.NewTriangleForPoints(a, b, d),
+		fauxgl.NewTriangleForPoints(a, d, c),
+		fauxgl.NewTriangleForPoints(b, c, d),
+	}
+}
+
+func analyze(triangles []*fauxgl.Triangle) *Analysis {
+	return Analyze(FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles)), 1e-6)
+}
+
+func TestAnalyzeWatertight(t *testing.T) {
+	a := analyze(tetrahedron())
+	if !a.Watertight() || a.Vertices != 4 || a.Shells != 1 {
+		t.Errorf("bad analysis %+v", a)
+	}
+}
+
+func TestAnalyzeProblems(t *testing.T) {
+	// open: one face missing
+	a := analyze(tetrahedron()[1:])
+	if len(a.Boundary) != 3 || a.Watertight() {
+		t.Errorf("got %d boundary edges, want 3", len(a.Boundary))
+	}
+
+	// one face flipped
+	triangles := tetrahedron()
+	t0 := triangles[0]
+	triangles[0] = fauxgl.NewTriangleForPoints(t0.V1.Position, t0.V3.Position, t0.V2.Position)
+	a = analyze(triangles)
+	if len(a.Winding) != 3 {
+		t.Errorf("got %d badly wound edges, want 3", len(a.Winding))
+	}
+
+	// a face repeated, and a second shell
+	triangles = append(tetrahedron(), tetrahedron()[0])
+	for _, t := range tetrahedron() {
+		offset := fauxgl.V(5, 0, 0)
+		triangles = append(triangles, fauxgl.NewTriangleForPoints(
+			t.V1.Position.Add(offset), t.V2.Position.Add(offset), t.V3.Position.Add(offset)))
+	}
+	a = analyze(triangles)
+	if len(a.NonManifold) != 3 {
+		t.Errorf("got %d non-manifold edges, want 3", len(a.NonManifold))
+	}
+	if a.Shells != 2 {
+		t.Errorf("got %d shells, want 2", a.Shells)
+	}
+}
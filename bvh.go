@@ -0,0 +1,261 @@
+package meshview
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// bvhLeafSize is the most triangles a BVH leaf holds
+const bvhLeafSize = 4
+
+// Triangle is three corner positions
+type Triangle [3]fauxgl.Vector
+
+// Box returns the bounding box of the triangle
+func (t Triangle) Box() fauxgl.Box {
+	return fauxgl.Box{Min: t[0].Min(t[1]).Min(t[2]), Max: t[0].Max(t[1]).Max(t[2])}
+}
+
+// BufferTriangles returns the triangles of a de-indexed buffer
+func BufferTriangles(buffer []float32) []Triangle {
+	triangles := make([]Triangle, len(buffer)/9)
+	for i := range triangles {
+		b := buffer[i*9:]
+		triangles[i] = Triangle{
+			fauxgl.V(float64(b[0]), float64(b[1]), float64(b[2])),
+			fauxgl.V(float64(b[3]), float64(b[4]), float64(b[5])),
+			fauxgl.V(float64(b[6]), float64(b[7]), float64(b[8])),
+		}
+	}
+	return triangles
+}
+
+type bvhNode struct {
+	Box         fauxgl.Box
+	Left, Right *bvhNode
+	Start, End  int // range of BVH.Order in a leaf
+}
+
+// BVH is a bounding volume hierarchy over triangles, for finding those near
+// a box, a ray or a point without testing every one
+type BVH struct {
+	Triangles []Triangle
+	Order     []int // triangle indices, grouped by leaf
+	root      *bvhNode
+}
+
+// NewBVH builds a BVH over triangles, splitting at the median along the
+// longest axis of each node
+func NewBVH(triangles []Triangle) *BVH {
+	b := BVH{Triangles: triangles, Order: make([]int, len(triangles))}
+	boxes := make([]fauxgl.Box, len(triangles))
+	centers := make([]fauxgl.Vector, len(triangles))
+	for i, t := range triangles {
+		b.Order[i] = i
+		boxes[i] = t.Box()
+		centers[i] = boxes[i].Center()
+	}
+	var build func(start, end int) *bvhNode
+	build = func(start, end int) *bvhNode {
+		order := b.Order[start:end]
+		node := bvhNode{Box: boxes[order[0]], Start: start, End: end}
+		for _, i := range order[1:] {
+			node.Box = node.Box.Extend(boxes[i])
+		}
+		if end-start <= bvhLeafSize {
+			return &node
+		}
+		size := node.Box.Size()
+		axis := func(v fauxgl.Vector) float64 { return v.X }
+		if size.Y > size.X && size.Y >= size.Z {
+			axis = func(v fauxgl.Vector) float64 { return v.Y }
+		} else if size.Z > size.X && size.Z > size.Y {
+			axis = func(v fauxgl.Vector) float64 { return v.Z }
+		}
+		sort.Slice(order, func(i, j int) bool {
+			return axis(centers[order[i]]) < axis(centers[order[j]])
+		})
+		mid := (start + end) / 2
+		node.Left = build(start, mid)
+		node.Right = build(mid, end)
+		return &node
+	}
+	if len(triangles) > 0 {
+		b.root = build(0, len(triangles))
+	}
+	return &b
+}
+
+// Query calls fn with the index of each triangle whose bounding box
+// intersects box
+func (b *BVH) Query(box fauxgl.Box, fn func(i int)) {
+	var visit func(*bvhNode)
+	visit = func(node *bvhNode) {
+		if node == nil || !node.Box.Intersects(box) {
+			return
+		}
+		if node.Left == nil {
+			for _, i := range b.Order[node.Start:node.End] {
+				if b.Triangles[i].Box().Intersects(box) {
+					fn(i)
+				}
+			}
+			return
+		}
+		visit(node.Left)
+		visit(node.Right)
+	}
+	visit(b.root)
+}
+
+// rayBox returns the distance along a ray to where it enters box, or +Inf
+// if it misses
+func rayBox(box fauxgl.Box, origin, dir fauxgl.Vector) float64 {
+	near, far := math.Inf(-1), math.Inf(1)
+	slab := func(lo, hi, o, d float64) {
+		if d == 0 {
+			if o < lo || o > hi {
+				near = math.Inf(1)
+			}
+			return
+		}
+		t1, t2 := (lo-o)/d, (hi-o)/d
+		near = math.Max(near, math.Min(t1, t2))
+		far = math.Min(far, math.Max(t1, t2))
+	}
+	slab(box.Min.X, box.Max.X, origin.X, dir.X)
+	slab(box.Min.Y, box.Max.Y, origin.Y, dir.Y)
+	slab(box.Min.Z, box.Max.Z, origin.Z, dir.Z)
+	if far < math.Max(near, 0) {
+		return math.Inf(1)
+	}
+	return near
+}
+
+// rayTriangle returns the distance along a ray to triangle t, or +Inf if
+// it misses
+func rayTriangle(origin, dir fauxgl.Vector, t Triangle) float64 {
+	e1 := t[1].Sub(t[0])
+	e2 := t[2].Sub(t[0])
+	h := dir.Cross(e2)
+	a := e1.Dot(h)
+	if a > -intersectEpsilon && a < intersectEpsilon {
+		return math.Inf(1) // parallel
+	}
+	f := 1 / a
+	s := origin.Sub(t[0])
+	u := f * s.Dot(h)
+	if u < 0 || u > 1 {
+		return math.Inf(1)
+	}
+	q := s.Cross(e1)
+	v := f * dir.Dot(q)
+	if v < 0 || u+v > 1 {
+		return math.Inf(1)
+	}
+	if d := f * e2.Dot(q); d > 0 {
+		return d
+	}
+	return math.Inf(1)
+}
+
+// Raycast returns the index of the nearest triangle other than skip hit by
+// the ray from origin along dir, and the distance to it in units of dir;
+// the index is -1 and the distance +Inf if nothing is hit
+func (b *BVH) Raycast(origin, dir fauxgl.Vector, skip int) (int, float64) {
+	hit, best := -1, math.Inf(1)
+	var visit func(*bvhNode)
+	visit = func(node *bvhNode) {
+		if node == nil || !(rayBox(node.Box, origin, dir) < best) {
+			return
+		}
+		if node.Left == nil {
+			for _, i := range b.Order[node.Start:node.End] {
+				if i == skip {
+					continue
+				}
+				if d := rayTriangle(origin, dir, b.Triangles[i]); d < best {
+					hit, best = i, d
+				}
+			}
+			return
+		}
+		visit(node.Left)
+		visit(node.Right)
+	}
+	visit(b.root)
+	return hit, best
+}
+
+// boxDistance returns the squared distance from p to box, zero inside it
+func boxDistance(box fauxgl.Box, p fauxgl.Vector) float64 {
+	d := box.Min.Sub(p).Max(p.Sub(box.Max)).Max(fauxgl.Vector{})
+	return d.Dot(d)
+}
+
+// closestPoint returns the point of triangle t closest to p
+func closestPoint(p fauxgl.Vector, t Triangle) fauxgl.Vector {
+	a, b, c := t[0], t[1], t[2]
+	ab, ac, ap := b.Sub(a), c.Sub(a), p.Sub(a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := p.Sub(b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	if vc := d1*d4 - d3*d2; vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.Add(ab.MulScalar(d1 / (d1 - d3)))
+	}
+	cp := p.Sub(c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	if vb := d5*d2 - d1*d6; vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.Add(ac.MulScalar(d2 / (d2 - d6)))
+	}
+	if va := d3*d6 - d5*d4; va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return b.Add(c.Sub(b).MulScalar((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	va := d3*d6 - d5*d4
+	vb := d5*d2 - d1*d6
+	vc := d1*d4 - d3*d2
+	denom := 1 / (va + vb + vc)
+	return a.Add(ab.MulScalar(vb * denom)).Add(ac.MulScalar(vc * denom))
+}
+
+// Nearest returns the index of the triangle closest to p and the closest
+// point on it; the index is -1 if there are no triangles
+func (b *BVH) Nearest(p fauxgl.Vector) (int, fauxgl.Vector) {
+	hit, best := -1, math.Inf(1)
+	var point fauxgl.Vector
+	var visit func(*bvhNode)
+	visit = func(node *bvhNode) {
+		if node == nil || !(boxDistance(node.Box, p) < best) {
+			return
+		}
+		if node.Left == nil {
+			for _, i := range b.Order[node.Start:node.End] {
+				q := closestPoint(p, b.Triangles[i])
+				if d := q.Sub(p); d.Dot(d) < best {
+					hit, best, point = i, d.Dot(d), q
+				}
+			}
+			return
+		}
+		// the nearer child first, to shrink best sooner
+		near, far := node.Left, node.Right
+		if boxDistance(far.Box, p) < boxDistance(near.Box, p) {
+			near, far = far, near
+		}
+		visit(near)
+		visit(far)
+	}
+	visit(b.root)
+	return hit, point
+}
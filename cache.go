@@ -0,0 +1,358 @@
+package meshview
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"unsafe"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+// Cache keeps parsed meshes and their slices on disk, keyed by path, size
+// and modification time, so reopening an unchanged file skips parsing and
+// slicing it. Entries are pruned least recently used first once they
+// total more than Limit bytes.
+type Cache struct {
+	Dir   string // empty turns the cache off
+	Limit int64  // zero or less for no limit
+}
+
+// ModelCache is the cache LoadModel and StreamModel read and slicing
+// writes to
+var ModelCache = &Cache{Limit: 1 << 30}
+
+// CachedSlices are finished layers and the plane they were sliced along
+type CachedSlices struct {
+	Plane   Plane
+	Layers  []slicer.Layer
+	Repairs []LayerRepair
+}
+
+// errCacheMiss is returned by Load when there is no entry for a key
+var errCacheMiss = errors.New("not in cache")
+
+// an entry is the magic, the float count, the box, the plane and the layer
+// count, then the floats of the buffer and each layer; all little endian
+const (
+	cacheMagic  = "MVC\x01"
+	cacheHeader = 4 + 8 + 6*8 + 6*8 + 4
+)
+
+// Key returns the name of the entry for the file at path, or "" if the
+// cache is off or the file cannot be read
+func (c *Cache) Key(path string) string {
+	if c.Dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d", abs, info.Size(), info.ModTime().UnixNano())
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".mvc")
+}
+
+// Load makes the model cached under key, with its cached layers filled in
+func (c *Cache) Load(key string) (*Model, error) {
+	if c.Dir == "" || key == "" {
+		return nil, errCacheMiss
+	}
+	path := c.path(key)
+	b, unmap, err := mapFile(path)
+	if os.IsNotExist(err) {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+	data, slices, err := decodeCache(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	// the buffer is mapped, so the model keeps a copy
+	data.Buffer = append([]float32(nil), data.Buffer...)
+	model := NewModel(data)
+	model.CacheKey = key
+	model.Cached = slices
+	model.SetPlane(model.Plane)
+	return model, nil
+}
+
+// Save writes data and slices under key, replacing any entry there, then
+// prunes the cache to its limit
+func (c *Cache) Save(key string, data *MeshData, slices *CachedSlices) error {
+	if c.Dir == "" || key == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = encodeCache(w, data, slices)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return c.Prune()
+}
+
+// Prune removes the least recently used entries until the rest fit in
+// Limit bytes
+func (c *Cache) Prune() error {
+	if c.Dir == "" || c.Limit <= 0 {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*.mvc"))
+	if err != nil {
+		return err
+	}
+	var infos []os.FileInfo
+	var total int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			infos = append(infos, info)
+			total += info.Size()
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= c.Limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.Dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// sameLevels reports whether layers are at exactly levels
+func sameLevels(layers []slicer.Layer, levels []float64) bool {
+	if len(layers) != len(levels) {
+		return false
+	}
+	for i, layer := range layers {
+		if layer.Z != levels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheWriter writes little endian values, keeping the first error
+type cacheWriter struct {
+	w   io.Writer
+	b   [8]byte
+	err error
+}
+
+func (w *cacheWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func (w *cacheWriter) u32(v uint32) {
+	binary.LittleEndian.PutUint32(w.b[:4], v)
+	w.write(w.b[:4])
+}
+
+func (w *cacheWriter) f64(v float64) {
+	binary.LittleEndian.PutUint64(w.b[:], math.Float64bits(v))
+	w.write(w.b[:])
+}
+
+func (w *cacheWriter) vector(v fauxgl.Vector) {
+	w.f64(v.X)
+	w.f64(v.Y)
+	w.f64(v.Z)
+}
+
+func encodeCache(out io.Writer, data *MeshData, slices *CachedSlices) error {
+	w := &cacheWriter{w: out}
+	w.write([]byte(cacheMagic))
+	binary.LittleEndian.PutUint64(w.b[:], uint64(len(data.Buffer)))
+	w.write(w.b[:])
+	w.vector(data.Box.Min)
+	w.vector(data.Box.Max)
+	w.vector(slices.Plane.Point)
+	w.vector(slices.Plane.Normal)
+	w.u32(uint32(len(slices.Layers)))
+	for _, f := range data.Buffer {
+		w.u32(math.Float32bits(f))
+	}
+	for i, layer := range slices.Layers {
+		r := slices.Repairs[i]
+		w.f64(layer.Z)
+		w.u32(uint32(r.Snapped))
+		w.u32(uint32(r.Dropped))
+		w.u32(uint32(r.Joined))
+		w.u32(uint32(len(r.Gaps)))
+		for _, gap := range r.Gaps {
+			w.vector(gap.Start)
+			w.vector(gap.End)
+		}
+		w.u32(uint32(len(layer.Paths)))
+		for _, path := range layer.Paths {
+			w.u32(uint32(len(path)))
+			for _, v := range path {
+				w.vector(v)
+			}
+		}
+	}
+	return w.err
+}
+
+// cacheReader reads little endian values from a mapped entry, failing
+// once it runs past the end
+type cacheReader struct {
+	b   []byte
+	err error
+}
+
+func (r *cacheReader) next(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *cacheReader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *cacheReader) f64() float64 {
+	if b := r.next(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *cacheReader) vector() fauxgl.Vector {
+	return fauxgl.Vector{X: r.f64(), Y: r.f64(), Z: r.f64()}
+}
+
+// count reads a length, checking there are at least size bytes for each
+func (r *cacheReader) count(size int) int {
+	n := int(r.u32())
+	if n > len(r.b)/size {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return n
+}
+
+// littleEndian is whether float32s can be read from an entry in place
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// floats32 returns b as float32s, sharing its memory where it can
+func floats32(b []byte) []float32 {
+	n := len(b) / 4
+	if n == 0 {
+		return nil
+	}
+	if littleEndian && uintptr(unsafe.Pointer(&b[0]))%4 == 0 {
+		return unsafe.Slice((*float32)(unsafe.Pointer(&b[0])), n)
+	}
+	floats := make([]float32, n)
+	for i := range floats {
+		floats[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return floats
+}
+
+// decodeCache reads an entry; the buffer of the data returned shares b
+func decodeCache(b []byte) (*MeshData, *CachedSlices, error) {
+	if len(b) < cacheHeader || string(b[:4]) != cacheMagic {
+		return nil, nil, errors.New("not a cache entry")
+	}
+	r := &cacheReader{b: b[4:]}
+	n := binary.LittleEndian.Uint64(r.next(8))
+	data := &MeshData{}
+	data.Box = fauxgl.Box{Min: r.vector(), Max: r.vector()}
+	slices := &CachedSlices{}
+	slices.Plane = Plane{r.vector(), r.vector()}
+	layers := r.count(8)
+	if n > uint64(len(r.b)/4) || n%9 != 0 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	data.Buffer = floats32(r.next(int(n) * 4))
+
+	slices.Layers = make([]slicer.Layer, layers)
+	slices.Repairs = make([]LayerRepair, layers)
+	for i := 0; i < layers && r.err == nil; i++ {
+		layer := &slices.Layers[i]
+		repair := &slices.Repairs[i]
+		layer.Z = r.f64()
+		repair.Snapped = int(r.u32())
+		repair.Dropped = int(r.u32())
+		repair.Joined = int(r.u32())
+		if gaps := r.count(48); gaps > 0 {
+			repair.Gaps = make([]Gap, gaps)
+			for j := range repair.Gaps {
+				repair.Gaps[j] = Gap{r.vector(), r.vector()}
+			}
+		}
+		layer.Paths = make([]slicer.Path, r.count(4))
+		for j := range layer.Paths {
+			path := make(slicer.Path, r.count(24))
+			for k := range path {
+				path[k] = r.vector()
+			}
+			layer.Paths[j] = path
+		}
+	}
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return data, slices, nil
+}
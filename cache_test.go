@@ -0,0 +1,95 @@
+package meshview
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "box.stl")
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 2, 3))))
+	if err := data.SaveSTL(path); err != nil {
+		t.Fatal(err)
+	}
+	if key := (&Cache{}).Key(path); key != "" {
+		t.Errorf("key %q with the cache off", key)
+	}
+
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	key := cache.Key(path)
+	if key == "" {
+		t.Fatal("no key")
+	}
+	if _, err := cache.Load(key); err != errCacheMiss {
+		t.Fatalf("load before save: %v", err)
+	}
+
+	model := NewModel(data)
+	model.CacheKey = key
+	for i, layer := range model.Slices {
+		square := slicer.Path{fauxgl.V(0, 0, layer.Z), fauxgl.V(1, 0, layer.Z), fauxgl.V(1, 2, layer.Z), fauxgl.V(0, 0, layer.Z)}
+		repair := LayerRepair{Snapped: i}
+		if i == 3 {
+			repair.Gaps = []Gap{{fauxgl.V(0, 0, layer.Z), fauxgl.V(1, 2, layer.Z)}}
+		}
+		model.AddSlice(SliceResult{model, model.Plane, model.SliceRun, i, slicer.Layer{Z: layer.Z, Paths: []slicer.Path{square}}, nil, LayerStats{}, repair})
+	}
+	slices := model.UncachedSlices()
+	if slices == nil {
+		t.Fatal("no slices to cache")
+	}
+	if err := cache.Save(key, data, slices); err != nil {
+		t.Fatal(err)
+	}
+	model.Cached = slices
+	if model.UncachedSlices() != nil {
+		t.Error("slices still uncached after saving")
+	}
+
+	cached, err := cache.Load(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.CacheKey != key || cached.Data.Count() != model.Data.Count() {
+		t.Errorf("loaded %d triangles under %q, want %d under %q", cached.Data.Count(), cached.CacheKey, model.Data.Count(), key)
+	}
+	if cached.Sliced != len(model.Slices) {
+		t.Fatalf("%d of %d layers sliced from the cache", cached.Sliced, len(model.Slices))
+	}
+	if !reflect.DeepEqual(cached.Slices, model.Slices) || !reflect.DeepEqual(cached.Repairs, model.Repairs) {
+		t.Error("cached layers differ")
+	}
+	if cached.UncachedSlices() != nil {
+		t.Error("cached model would be written back")
+	}
+
+	// slices along another plane are not reused
+	cached.SetPlane(NewPlane(model.Plane.Point, PlaneX.Normal))
+	if cached.Sliced != 0 {
+		t.Errorf("%d layers along x taken from the cache", cached.Sliced)
+	}
+
+	// a changed file has a new key
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Key(path) == key {
+		t.Error("key unchanged after the file changed")
+	}
+
+	cache.Limit = 1
+	if err := cache.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Load(key); err != errCacheMiss {
+		t.Errorf("load after pruning: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package meshview
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// ChunkSize is about the most triangles SplitChunks puts in a chunk
+var ChunkSize = 1 << 16
+
+// Chunk is a spatially coherent piece of an indexed mesh, indexed on its
+// own, with the bounding box of its triangles
+type Chunk struct {
+	Box      fauxgl.Box
+	Vertices []float32
+	Indices  []uint32
+}
+
+// gridCells returns the cells along each axis of a grid over size with
+// cells of side s
+func gridCells(size fauxgl.Vector, s float64) [3]int {
+	cells := func(e float64) int {
+		return int(math.Max(1, math.Ceil(e/s)))
+	}
+	return [3]int{cells(size.X), cells(size.Y), cells(size.Z)}
+}
+
+// SplitChunks divides the triangles of m among the cells of a grid, by
+// their centers, sized so that cells average about size triangles. Empty
+// cells are dropped.
+func SplitChunks(m *IndexedMeshData, size int) []Chunk {
+	count := len(m.Indices) / 3
+	if count == 0 {
+		return nil
+	}
+	want := int(math.Ceil(float64(count) / float64(size)))
+
+	// the smallest cell side giving no more than want cells, so flat
+	// meshes split in two dimensions rather than three
+	extent := m.Box.Size()
+	lo, hi := 0.0, extent.MaxComponent()
+	if hi == 0 {
+		hi = 1
+	}
+	for i := 0; i < 50; i++ {
+		mid := (lo + hi) / 2
+		c := gridCells(extent, mid)
+		if c[0]*c[1]*c[2] > want {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	grid := gridCells(extent, hi)
+	side := hi
+
+	cell := func(p fauxgl.Vector) int {
+		q := p.Sub(m.Box.Min).DivScalar(side)
+		x := int(math.Min(math.Max(q.X, 0), float64(grid[0]-1)))
+		y := int(math.Min(math.Max(q.Y, 0), float64(grid[1]-1)))
+		z := int(math.Min(math.Max(q.Z, 0), float64(grid[2]-1)))
+		return (z*grid[1]+y)*grid[0] + x
+	}
+	cells := make(map[int][]int)
+	for i := 0; i < count; i++ {
+		a := m.Vertex(m.Indices[i*3])
+		b := m.Vertex(m.Indices[i*3+1])
+		c := m.Vertex(m.Indices[i*3+2])
+		k := cell(a.Add(b).Add(c).DivScalar(3))
+		cells[k] = append(cells[k], i)
+	}
+	keys := make([]int, 0, len(cells))
+	for k := range cells {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	chunks := make([]Chunk, 0, len(keys))
+	for _, k := range keys {
+		var chunk Chunk
+		local := make(map[uint32]uint32)
+		for _, i := range cells[k] {
+			for _, index := range m.Indices[i*3 : i*3+3] {
+				j, ok := local[index]
+				if !ok {
+					j = uint32(len(chunk.Vertices) / 3)
+					local[index] = j
+					chunk.Vertices = append(chunk.Vertices, m.Vertices[index*3:index*3+3]...)
+				}
+				chunk.Indices = append(chunk.Indices, j)
+			}
+		}
+		chunk.Box = boxForData(chunk.Vertices)
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// BoxVisible reports whether any of box may be inside the view frustum of
+// matrix; boxes wholly beyond one of the clip planes are not
+func BoxVisible(matrix fauxgl.Matrix, box fauxgl.Box) bool {
+	var outside [6]int
+	for i := 0; i < 8; i++ {
+		p := box.Min
+		if i&1 != 0 {
+			p.X = box.Max.X
+		}
+		if i&2 != 0 {
+			p.Y = box.Max.Y
+		}
+		if i&4 != 0 {
+			p.Z = box.Max.Z
+		}
+		v := matrix.MulPositionW(p)
+		for j, out := range [6]bool{v.X < -v.W, v.X > v.W, v.Y < -v.W, v.Y > v.W, v.Z < -v.W, v.Z > v.W} {
+			if out {
+				outside[j]++
+			}
+		}
+	}
+	for _, n := range outside {
+		if n == 8 {
+			return false
+		}
+	}
+	return true
+}
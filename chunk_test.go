@@ -0,0 +1,51 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestSplitChunks(t *testing.T) {
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(gridBox(fauxgl.V(0, 0, 0), fauxgl.V(4, 4, 4), 8)))
+	indexed := data.Indexed()
+	chunks := SplitChunks(indexed, 100)
+	want := (768 + 99) / 100
+	if len(chunks) < 2 || len(chunks) > want {
+		t.Errorf("%d chunks, want 2 to %d", len(chunks), want)
+	}
+	triangles := 0
+	for i, c := range chunks {
+		triangles += len(c.Indices) / 3
+		for _, index := range c.Indices {
+			if int(index) >= len(c.Vertices)/3 {
+				t.Fatalf("chunk %d index %d out of range", i, index)
+			}
+		}
+		if !data.Box.Contains(c.Box.Min) || !data.Box.Contains(c.Box.Max) {
+			t.Errorf("chunk %d box %v outside the mesh", i, c.Box)
+		}
+	}
+	if triangles != 768 {
+		t.Errorf("chunks hold %d triangles, want 768", triangles)
+	}
+
+	// a flat mesh splits across its face only
+	flat := FauxMesh2MeshData(fauxgl.NewTriangleMesh(gridBox(fauxgl.V(0, 0, 0), fauxgl.V(4, 4, 4), 8)[:128]))
+	if chunks := SplitChunks(flat.Indexed(), 32); len(chunks) != 4 {
+		t.Errorf("flat mesh in %d chunks, want 4", len(chunks))
+	}
+}
+
+func TestBoxVisible(t *testing.T) {
+	m := fauxgl.Identity()
+	if !BoxVisible(m, fauxgl.Box{Min: fauxgl.V(-2, -2, -2), Max: fauxgl.V(2, 2, 2)}) {
+		t.Errorf("box around the view culled")
+	}
+	if !BoxVisible(m, fauxgl.Box{Min: fauxgl.V(0.5, 0.5, 0), Max: fauxgl.V(3, 3, 0)}) {
+		t.Errorf("box across a corner culled")
+	}
+	if BoxVisible(m, fauxgl.Box{Min: fauxgl.V(1.5, -1, -1), Max: fauxgl.V(3, 1, 1)}) {
+		t.Errorf("box to the right of the view drawn")
+	}
+}
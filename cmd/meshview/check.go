@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/meshview"
+)
+
+// checkCommand reports whether a mesh is watertight, exiting 1 if not
+func checkCommand(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	tolerance := flags.Float64("tolerance", 0, "vertex welding distance (default scaled to the mesh)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview check [flags] model.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *tolerance == 0 {
+		*tolerance = meshview.WeldTolerance(data.Box)
+	}
+	a := meshview.Analyze(data, *tolerance)
+	a.Print(os.Stdout)
+	if !a.Watertight() {
+		os.Exit(1)
+	}
+}
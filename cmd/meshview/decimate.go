@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/meshview"
+)
+
+// decimateCommand reduces the triangle count of a mesh and saves it
+func decimateCommand(args []string) {
+	flags := flag.NewFlagSet("decimate", flag.ExitOnError)
+	output := flags.String("o", "", "output stl file")
+	target := flags.Int("target", 0, "triangles to keep")
+	ratio := flags.Float64("ratio", 0, "fraction of the triangles to keep, if no target")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview decimate [flags] in.stl -o out.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 || *output == "" || (*target <= 0 && *ratio <= 0) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	triangles := len(data.Buffer) / 9
+	if *target <= 0 {
+		*target = int(float64(triangles) * *ratio)
+	}
+	decimated := meshview.Decimate(data, *target)
+	fmt.Printf("triangles %d -> %d\n", triangles, len(decimated.Buffer)/9)
+	if err := decimated.SaveSTL(*output); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/meshview"
+)
+
+// diffCommand compares a mesh with a reference, printing the deviation and
+// showing it in the viewer unless asked not to
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	align := flags.String("align", "centers", "alignment: none, centers or icp")
+	scale := flags.Float64("scale", 0, "deviation shown fully red or blue (default the largest)")
+	noView := flags.Bool("n", false, "print the deviation without opening the viewer")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview diff [flags] a.stl b.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	alignment, err := meshview.ParseAlignment(*align)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !*noView {
+		meshview.RunWith(meshview.ViewerOptions{
+			Compare:        paths[1],
+			Alignment:      alignment,
+			DeviationScale: *scale,
+			Shade:          meshview.ShadeDeviation,
+		}, paths[0])
+		return
+	}
+	a, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := meshview.LoadMesh(paths[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	a = meshview.TransformData(a, meshview.Align(a, b, alignment))
+	_, r := meshview.Deviation(a, b)
+	r.Print(os.Stdout)
+}
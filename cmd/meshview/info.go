@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/meshview"
+)
+
+// infoCommand prints the size and mass properties of a mesh
+func infoCommand(args []string) {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	density := flags.Float64("density", 0, "material density, to compute mass")
+	asJSON := flags.Bool("json", false, "print json instead of text")
+	overhang := overhangFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview info [flags] model.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	triangles := len(data.Buffer) / 9
+	p := data.MassProperties(*density)
+	_, support := meshview.FindOverhangs(data, overhang())
+	shells := meshview.FindShells(data)
+	if *asJSON {
+		info := struct {
+			Triangles    int        `json:"triangles"`
+			Box          fauxgl.Box `json:"box"`
+			OverhangArea float64    `json:"overhang_area"`
+			Shells       int        `json:"shells"`
+			meshview.MassProperties
+		}{triangles, data.Box, support, len(shells), p}
+		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	size := data.Box.Size()
+	fmt.Printf("triangles %d\n", triangles)
+	fmt.Printf("size      %g %g %g\n", size.X, size.Y, size.Z)
+	p.Print(os.Stdout)
+	fmt.Printf("overhang  %g\n", support)
+	if len(shells) > 1 {
+		meshview.PrintShells(os.Stdout, shells)
+	}
+}
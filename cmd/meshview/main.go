@@ -1,16 +1,125 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
 	"os"
 
+	"github.com/fogleman/fauxgl"
 	"github.com/fogleman/meshview"
 )
 
 func main() {
 	args := os.Args[1:]
 	if len(args) > 0 {
-		meshview.Run(args[0])
-	} else {
-		meshview.Run("")
+		switch args[0] {
+		case "slice":
+			sliceCommand(args[1:])
+			return
+		case "raster":
+			rasterCommand(args[1:])
+			return
+		case "check":
+			checkCommand(args[1:])
+			return
+		case "info":
+			infoCommand(args[1:])
+			return
+		case "repair":
+			repairCommand(args[1:])
+			return
+		case "decimate":
+			decimateCommand(args[1:])
+			return
+		case "diff":
+			diffCommand(args[1:])
+			return
+		case "thickness":
+			thicknessCommand(args[1:])
+			return
+		}
 	}
+	viewCommand(args)
+}
+
+// viewCommand opens the viewer on a model, if given one
+func viewCommand(args []string) {
+	flags := flag.NewFlagSet("meshview", flag.ExitOnError)
+	overhang := overhangFlags(flags)
+	minThickness := flags.Float64("min-thickness", meshview.DefaultMinThickness, "thinnest wall expected to print")
+	lod := flags.Int("lod", meshview.LodTarget, "triangles drawn while moving larger meshes, 0 to always draw all")
+	cache := cacheFlags(flags)
+	layout := flags.String("layout", "original", "placement of several models: original or side")
+	watch := flags.Bool("watch", true, "reload models when their files change")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview [flags] [model.stl ...]")
+		fmt.Fprintln(os.Stderr, "       meshview slice|raster|check|info|repair|decimate|thickness|diff ...")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	l, err := meshview.ParseLayout(*layout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *lod == 0 {
+		*lod = -1
+	}
+	meshview.RunWith(meshview.ViewerOptions{
+		Layout:       l,
+		Watch:        *watch,
+		LodTarget:    *lod,
+		Overhang:     overhang(),
+		MinThickness: *minThickness,
+		Cache:        cache(),
+	}, paths...)
+}
+
+// overhangFlags adds the overhang analysis flags to flags, returning a
+// function for the options they set once parsed
+func overhangFlags(flags *flag.FlagSet) func() meshview.OverhangOptions {
+	d := meshview.DefaultOverhangOptions
+	up := flags.String("up", fmt.Sprintf("%g,%g,%g", d.Up.X, d.Up.Y, d.Up.Z), "build direction as x,y,z")
+	warn := flags.Float64("warn-angle", d.WarnAngle, "overhang angle from vertical past which faces are steep")
+	critical := flags.Float64("critical-angle", d.CriticalAngle, "overhang angle from vertical past which faces need support")
+	return func() meshview.OverhangOptions {
+		v, err := parseVector(*up)
+		if err != nil || v == (fauxgl.Vector{}) {
+			fmt.Fprintln(os.Stderr, "invalid -up:", *up)
+			os.Exit(2)
+		}
+		return meshview.OverhangOptions{Up: v, WarnAngle: *warn, CriticalAngle: *critical}
+	}
+}
+
+// cacheFlags adds the mesh cache flags to flags, returning a function for
+// the cache they configure once parsed
+func cacheFlags(flags *flag.FlagSet) func() *meshview.Cache {
+	dir := flags.String("cache", os.Getenv("MESHVIEW_CACHE"), "directory caching parsed meshes and slices, none if empty (default $MESHVIEW_CACHE)")
+	limit := flags.Int64("cache-limit", meshview.ModelCache.Limit>>20, "cache size limit in megabytes, 0 for none")
+	return func() *meshview.Cache {
+		return &meshview.Cache{Dir: *dir, Limit: *limit << 20}
+	}
+}
+
+// parseArgs parses flags wherever they appear in args, returning the
+// remaining positional arguments
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseVector parses an "x,y,z" flag value
+func parseVector(s string) (fauxgl.Vector, error) {
+	var v fauxgl.Vector
+	_, err := fmt.Sscanf(s, "%g,%g,%g", &v.X, &v.Y, &v.Z)
+	return v, err
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/meshview"
+)
+
+// rasterCommand writes the slices of a mesh as a png stack for resin printers
+func rasterCommand(args []string) {
+	flags := flag.NewFlagSet("raster", flag.ExitOnError)
+	layerHeight := flags.Float64("layer-height", 0.05, "distance between layers")
+	width := flags.Int("width", 2560, "image width in pixels")
+	height := flags.Int("height", 1440, "image height in pixels")
+	pixelSize := flags.Float64("pixel-size", 0.047, "pixel pitch in millimeters")
+	nonZero := flags.Bool("nonzero", false, "fill by nonzero winding instead of even-odd")
+	antiAlias := flags.Int("aa", 1, "anti-aliasing samples per pixel along each axis")
+	output := flags.String("o", "layers", "output directory, or a .zip file")
+	cache := cacheFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview raster [flags] model.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	meshview.ModelCache = cache()
+
+	model, err := meshview.LoadModel(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	// start half a layer above the bottom, as the printer exposes it
+	box := model.Data.Box
+	model.LayerHeight = *layerHeight
+	model.SetPlane(meshview.NewPlane(fauxgl.V(0, 0, box.Min.Z+*layerHeight/2), fauxgl.V(0, 0, 1)))
+	model.SliceWait()
+
+	opt := meshview.RasterOptions{
+		Width:     *width,
+		Height:    *height,
+		PixelSize: *pixelSize,
+		Center:    box.Center(),
+		NonZero:   *nonZero,
+		AntiAlias: *antiAlias,
+	}
+	if err := meshview.SavePNGStack(*output, model.Slices, opt); err != nil {
+		log.Fatal(err)
+	}
+}
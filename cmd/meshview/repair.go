@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/meshview"
+)
+
+// repairCommand welds, cleans, reorients and fills a mesh and saves it
+func repairCommand(args []string) {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	output := flags.String("o", "", "output stl file")
+	tolerance := flags.Float64("tolerance", 0, "vertex welding distance (default scaled to the mesh)")
+	noNormals := flags.Bool("keep-normals", false, "don't reorient faces")
+	noFill := flags.Bool("keep-holes", false, "don't fill holes")
+	maxHole := flags.Int("max-hole", 0, "largest hole to fill, in edges (default any size)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview repair [flags] in.stl -o out.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 || *output == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	opt := meshview.DefaultRepairOptions(data)
+	if *tolerance != 0 {
+		opt.Tolerance = *tolerance
+	}
+	opt.FixNormals = !*noNormals
+	opt.FillHoles = !*noFill
+	opt.MaxHoleEdges = *maxHole
+	repaired, report := meshview.Repair(data, opt)
+	report.Print(os.Stdout)
+	if err := repaired.SaveSTL(*output); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/meshview"
+	"github.com/fogleman/slicer"
+)
+
+// sliceCommand writes the slices of a mesh as svg, dxf or json
+func sliceCommand(args []string) {
+	flags := flag.NewFlagSet("slice", flag.ExitOnError)
+	layerHeight := flags.Float64("layer-height", 0, "distance between layers (default 250 layers in all)")
+	format := flags.String("format", "svg", "output format for a directory: svg, dxf or json")
+	output := flags.String("o", ".", "output file, formatted by extension, or directory for one file per layer")
+	normal := flags.String("normal", "0,0,1", "slicing plane normal as x,y,z")
+	cache := cacheFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview slice [flags] model.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	meshview.ModelCache = cache()
+	n, err := parseVector(*normal)
+	if err != nil {
+		log.Fatalf("bad normal %q: %v", *normal, err)
+	}
+
+	model, err := meshview.LoadModel(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	// start half a layer above the bottom, as a printer would
+	plane := meshview.NewPlane(fauxgl.Vector{}, n)
+	lo, _ := plane.Extent(model.Data.Buffer)
+	plane.Point = plane.Normal.MulScalar(lo + *layerHeight/2)
+	model.LayerHeight = *layerHeight
+	model.SetPlane(plane)
+	model.SliceWait()
+
+	if !layerDir(*output) {
+		if err := meshview.SaveLayers(*output, model.Slices); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := os.MkdirAll(*output, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for i, layer := range model.Slices {
+		path := filepath.Join(*output, fmt.Sprintf("layer%04d.%s", i, *format))
+		if err := meshview.SaveLayers(path, []slicer.Layer{layer}); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// layerDir reports whether output names a directory to write a file per
+// layer into: one that exists, ends in a separator or has no extension
+func layerDir(output string) bool {
+	if info, err := os.Stat(output); err == nil {
+		return info.IsDir()
+	}
+	if strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator)) {
+		return true
+	}
+	return filepath.Ext(output) == ""
+}
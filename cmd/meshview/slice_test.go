@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayerDir(t *testing.T) {
+	dir := t.TempDir()
+	dotted := filepath.Join(dir, "v1.2")
+	if err := os.Mkdir(dotted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "layers.svg")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for output, want := range map[string]bool{
+		".":                               true, // the default
+		"..":                              true,
+		dotted:                            true,
+		filepath.Join(dir, "new"):         true,
+		filepath.Join(dir, "new.d") + "/": true,
+		file:                              false,
+		filepath.Join(dir, "out.dxf"):     false,
+	} {
+		if got := layerDir(output); got != want {
+			t.Errorf("layerDir(%q) = %v, want %v", output, got, want)
+		}
+	}
+}
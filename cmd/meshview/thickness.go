@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fogleman/meshview"
+)
+
+// thicknessCommand reports the walls of a mesh thinner than a minimum,
+// exiting 1 if there are any
+func thicknessCommand(args []string) {
+	flags := flag.NewFlagSet("thickness", flag.ExitOnError)
+	minimum := flags.Float64("min", meshview.DefaultMinThickness, "thinnest wall expected to print")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: meshview thickness [flags] model.stl")
+		flags.PrintDefaults()
+	}
+	paths := parseArgs(flags, args)
+	if len(paths) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := meshview.LoadMesh(paths[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := meshview.SummarizeThickness(data, meshview.Thickness(data), *minimum)
+	r.Print(os.Stdout)
+	if r.Thin() {
+		os.Exit(1)
+	}
+}
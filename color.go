@@ -0,0 +1,56 @@
+package meshview
+
+import (
+	"math"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// FaceColors makes a per-vertex color buffer for n de-indexed triangles,
+// giving all three corners of triangle i the color color(i)
+func FaceColors(n int, color func(i int) fauxgl.Vector) []float32 {
+	colors := make([]float32, n*9)
+	for i := 0; i < n; i++ {
+		p := color(i).Points()
+		for j := 0; j < 3; j++ {
+			copy(colors[i*9+j*3:], p)
+		}
+	}
+	return colors
+}
+
+// Ramp returns a color from blue at 0 through green to red at 1
+func Ramp(t float64) fauxgl.Vector {
+	t = math.Max(0, math.Min(1, t))
+	if t < 0.5 {
+		return fauxgl.V(0, t*2, 1-t*2)
+	}
+	return fauxgl.V(t*2-1, 2-t*2, 0)
+}
+
+// Palette returns the i-th of a sequence of distinct, evenly spread colors
+func Palette(i int) fauxgl.Vector {
+	// step the hue by the golden ratio so neighbors differ the most
+	h := math.Mod(float64(i)*0.618033988749895, 1)
+	return HSV(h, 0.65, 0.9)
+}
+
+// HSV converts a hue, saturation and value, all 0 to 1, to a color
+func HSV(h, s, v float64) fauxgl.Vector {
+	h = math.Mod(h, 1) * 6
+	f := h - math.Floor(h)
+	p, q, t := v*(1-s), v*(1-s*f), v*(1-s*(1-f))
+	switch int(h) {
+	case 0:
+		return fauxgl.V(v, t, p)
+	case 1:
+		return fauxgl.V(q, v, p)
+	case 2:
+		return fauxgl.V(p, v, t)
+	case 3:
+		return fauxgl.V(p, q, v)
+	case 4:
+		return fauxgl.V(t, p, v)
+	}
+	return fauxgl.V(v, p, q)
+}
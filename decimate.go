@@ -0,0 +1,398 @@
+package meshview
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// boundaryWeight scales the planes that hold open edges in place, relative
+// to the faces beside them
+const boundaryWeight = 1000
+
+// lengthWeight adds a little of each edge's length to its cost, so that
+// across flat areas, where collapses cost nothing, short edges go first
+// rather than one vertex swallowing all around it
+const lengthWeight = 1e-6
+
+// quadric is a symmetric 4x4 error matrix, its upper triangle row by row
+type quadric [10]float64
+
+// planeQuadric returns the quadric measuring squared distance to the plane
+// n.p + d = 0, n normalized
+func planeQuadric(n fauxgl.Vector, d float64) quadric {
+	a, b, c := n.X, n.Y, n.Z
+	return quadric{a * a, a * b, a * c, a * d, b * b, b * c, b * d, c * c, c * d, d * d}
+}
+
+func (q quadric) add(r quadric) quadric {
+	for i := range q {
+		q[i] += r[i]
+	}
+	return q
+}
+
+func (q quadric) scale(s float64) quadric {
+	for i := range q {
+		q[i] *= s
+	}
+	return q
+}
+
+// error returns the quadric error of moving to v
+func (q quadric) error(v fauxgl.Vector) float64 {
+	x, y, z := v.X, v.Y, v.Z
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z + q[9]
+}
+
+// optimum returns the point of least error, if the quadric has one
+func (q quadric) optimum() (fauxgl.Vector, bool) {
+	a, b, c := q[0], q[1], q[2]
+	d, e, f := q[1], q[4], q[5]
+	g, h, i := q[2], q[5], q[7]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	trace := a + e + i
+	if math.Abs(det) <= 1e-9*trace*trace*trace {
+		return fauxgl.Vector{}, false
+	}
+	// cramer's rule on A v = -(q3, q6, q8)
+	r := fauxgl.V(-q[3], -q[6], -q[8])
+	x := (r.X*(e*i-f*h) - b*(r.Y*i-f*r.Z) + c*(r.Y*h-e*r.Z)) / det
+	y := (a*(r.Y*i-f*r.Z) - r.X*(d*i-f*g) + c*(d*r.Z-r.Y*g)) / det
+	z := (a*(e*r.Z-r.Y*h) - b*(d*r.Z-r.Y*g) + r.X*(d*h-e*g)) / det
+	return fauxgl.V(x, y, z), true
+}
+
+// collapse is a candidate edge collapse, valid while both vertices are at
+// the versions it was made with
+type collapse struct {
+	Cost     float64
+	A, B     int32
+	VA, VB   uint32
+	Position fauxgl.Vector
+}
+
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int      { return len(h) }
+func (h collapseHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h collapseHeap) Less(i, j int) bool {
+	if h[i].Cost != h[j].Cost {
+		return h[i].Cost < h[j].Cost
+	}
+	if h[i].A != h[j].A {
+		return h[i].A < h[j].A
+	}
+	return h[i].B < h[j].B
+}
+
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(collapse)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Decimate returns a copy of data reduced to about target triangles by
+// collapsing the edges whose removal changes the surface least, measured
+// by quadric error. Open edges are held in place.
+func Decimate(data *MeshData, target int) *MeshData {
+	vertices, indices := Weld(data.Buffer, WeldTolerance(data.Box))
+	buffer, _ := decimate(vertices, indices, target)
+	return decimatedData(buffer)
+}
+
+// DecimateIndexed decimates data as Decimate does, without welding it
+// again, and also returns the index of the triangle of data each triangle
+// left came from
+func DecimateIndexed(data *IndexedMeshData, target int) (*MeshData, []int) {
+	vertices := make([]fauxgl.Vector, len(data.Vertices)/3)
+	for i := range vertices {
+		v := data.Vertices[i*3:]
+		vertices[i] = fauxgl.V(float64(v[0]), float64(v[1]), float64(v[2]))
+	}
+	buffer, origins := decimate(vertices, data.Indices, target)
+	return decimatedData(buffer), origins
+}
+
+// decimatedData returns buffer with its box
+func decimatedData(buffer []float32) *MeshData {
+	if len(buffer) == 0 {
+		return &MeshData{buffer, fauxgl.Box{}, nil}
+	}
+	return &MeshData{buffer, boxForData(buffer), nil}
+}
+
+// holds reports whether vertex v is among held
+func holds(held []int32, v int32) bool {
+	for _, w := range held {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// faceEdge is an undirected edge, its lower vertex in the high bits, and a
+// face it belongs to
+type faceEdge struct {
+	Key  uint64
+	Face int32
+}
+
+// decimate collapses the edges of the triangles indexing pos, moving pos,
+// until about target are left, returning those left as a buffer and the
+// index of the triangle each came from
+func decimate(pos []fauxgl.Vector, indices []uint32, target int) ([]float32, []int) {
+	faces := make([][3]int32, 0, len(indices)/3)
+	origins := make([]int, 0, len(indices)/3)
+	for i := 0; i+2 < len(indices); i += 3 {
+		f := [3]int32{int32(indices[i]), int32(indices[i+1]), int32(indices[i+2])}
+		if f[0] != f[1] && f[1] != f[2] && f[2] != f[0] {
+			faces = append(faces, f)
+			origins = append(origins, i/3)
+		}
+	}
+
+	// the faces of each vertex, laid out in one array
+	quadrics := make([]quadric, len(pos))
+	start := make([]int32, len(pos)+1)
+	edges := make([]faceEdge, 0, len(faces)*3)
+	for i, f := range faces {
+		a, b, c := pos[f[0]], pos[f[1]], pos[f[2]]
+		n := b.Sub(a).Cross(c.Sub(a))
+		area := n.Length() / 2
+		if area > 0 {
+			n = n.Normalize()
+			q := planeQuadric(n, -n.Dot(a)).scale(area)
+			for _, v := range f {
+				quadrics[v] = quadrics[v].add(q)
+			}
+		}
+		for j, v := range f {
+			start[v+1]++
+			k := edgeKey(uint32(v), uint32(f[(j+1)%3]))
+			edges = append(edges, faceEdge{uint64(k[0])<<32 | uint64(k[1]), int32(i)})
+		}
+	}
+	for v := range pos {
+		start[v+1] += start[v]
+	}
+	flat := make([]int32, start[len(pos)])
+	next := append([]int32(nil), start[:len(pos)]...)
+	for i, f := range faces {
+		for _, v := range f {
+			flat[next[v]] = int32(i)
+			next[v]++
+		}
+	}
+	vfaces := make([][]int32, len(pos))
+	for v := range vfaces {
+		vfaces[v] = flat[start[v]:start[v+1]:start[v+1]]
+	}
+
+	// open edges, used by one face, get a plane at right angles to it
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].Key < edges[j].Key || edges[i].Key == edges[j].Key && edges[i].Face < edges[j].Face
+	})
+	var keys []uint64
+	for i := 0; i < len(edges); {
+		j := i + 1
+		for j < len(edges) && edges[j].Key == edges[i].Key {
+			j++
+		}
+		keys = append(keys, edges[i].Key)
+		if j-i == 1 {
+			f := faces[edges[i].Face]
+			p, q := int32(edges[i].Key>>32), int32(uint32(edges[i].Key))
+			a, b, c := pos[f[0]], pos[f[1]], pos[f[2]]
+			n := b.Sub(a).Cross(c.Sub(a))
+			e := pos[q].Sub(pos[p])
+			if m := e.Cross(n); m.Length() != 0 {
+				m = m.Normalize()
+				bq := planeQuadric(m, -m.Dot(pos[p])).scale(boundaryWeight * e.Dot(e))
+				quadrics[p] = quadrics[p].add(bq)
+				quadrics[q] = quadrics[q].add(bq)
+			}
+		}
+		i = j
+	}
+	edges = nil
+
+	version := make([]uint32, len(pos))
+	merged := make([]bool, len(pos))
+	// held are the partners of collapses ruled out by the faces around a
+	// vertex, tried again once those faces change
+	held := make([][]int32, len(pos))
+	deadFace := make([]bool, len(faces))
+	alive := len(faces)
+
+	candidate := func(a, b int32) collapse {
+		q := quadrics[a].add(quadrics[b])
+		p, ok := q.optimum()
+		if !ok {
+			p = pos[a]
+			mid := pos[a].Add(pos[b]).DivScalar(2)
+			for _, v := range []fauxgl.Vector{pos[b], mid} {
+				if q.error(v) < q.error(p) {
+					p = v
+				}
+			}
+		}
+		e := pos[b].Sub(pos[a]).Dot(pos[b].Sub(pos[a]))
+		return collapse{q.error(p) + lengthWeight*e*e, a, b, version[a], version[b], p}
+	}
+	// queued in a fixed order, so ties collapse the same way every time
+	h := make(collapseHeap, 0, len(keys))
+	for _, k := range keys {
+		h = append(h, candidate(int32(k>>32), int32(uint32(k))))
+	}
+	keys = nil
+	heap.Init(&h)
+
+	// neighbors appends the vertices sharing a live face with v to ring,
+	// leaving them marked with the returned stamp
+	stamps := make([]uint32, len(pos))
+	stamp := uint32(0)
+	neighbors := func(v int32, ring []int32) ([]int32, uint32) {
+		stamp++
+		for _, i := range vfaces[v] {
+			if deadFace[i] {
+				continue
+			}
+			for _, w := range faces[i] {
+				if w != v && stamps[w] != stamp {
+					stamps[w] = stamp
+					ring = append(ring, w)
+				}
+			}
+		}
+		return ring, stamp
+	}
+	var ring []int32
+	// flips reports whether moving v to p turns any face of v other than
+	// those shared with w over, or squashes it flat
+	flips := func(v, w int32, p fauxgl.Vector) bool {
+		for _, i := range vfaces[v] {
+			f := faces[i]
+			if deadFace[i] || f[0] == w || f[1] == w || f[2] == w {
+				continue
+			}
+			before := pos[f[1]].Sub(pos[f[0]]).Cross(pos[f[2]].Sub(pos[f[0]]))
+			var corners [3]fauxgl.Vector
+			for j, u := range f {
+				corners[j] = pos[u]
+				if u == v {
+					corners[j] = p
+				}
+			}
+			after := corners[1].Sub(corners[0]).Cross(corners[2].Sub(corners[0]))
+			if after.Dot(before) <= 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	for alive > target && h.Len() > 0 {
+		c := heap.Pop(&h).(collapse)
+		a, b := c.A, c.B
+		if merged[a] || merged[b] || version[a] != c.VA || version[b] != c.VB {
+			continue
+		}
+		// the edge's own faces, and no other common neighbors, keep the
+		// surface manifold
+		shared := 0
+		for _, i := range vfaces[a] {
+			f := faces[i]
+			if !deadFace[i] && (f[0] == b || f[1] == b || f[2] == b) {
+				shared++
+			}
+		}
+		var marked uint32
+		ring, marked = neighbors(a, ring[:0])
+		common := 0
+		for _, i := range vfaces[b] {
+			if deadFace[i] {
+				continue
+			}
+			for _, w := range faces[i] {
+				if w != b && stamps[w] == marked {
+					stamps[w] = 0
+					common++
+				}
+			}
+		}
+		if shared == 0 {
+			continue // no longer an edge
+		}
+		if common > shared || flips(a, b, c.Position) || flips(b, a, c.Position) {
+			if !holds(held[a], b) {
+				held[a] = append(held[a], b)
+			}
+			continue
+		}
+
+		pos[a] = c.Position
+		quadrics[a] = quadrics[a].add(quadrics[b])
+		for _, i := range vfaces[b] {
+			if deadFace[i] {
+				continue
+			}
+			f := &faces[i]
+			if f[0] == a || f[1] == a || f[2] == a {
+				deadFace[i] = true
+				alive--
+				continue
+			}
+			for j := range f {
+				if f[j] == b {
+					f[j] = a
+				}
+			}
+			vfaces[a] = append(vfaces[a], i)
+		}
+		live := vfaces[a][:0]
+		for _, i := range vfaces[a] {
+			if !deadFace[i] {
+				live = append(live, i)
+			}
+		}
+		vfaces[a] = live
+		vfaces[b] = nil
+		merged[b] = true
+		version[a]++
+		held[b] = nil
+		ring, _ = neighbors(a, ring[:0])
+		for _, w := range ring {
+			heap.Push(&h, candidate(a, w))
+		}
+		ring = append(ring, a)
+		for _, w := range ring {
+			for _, x := range held[w] {
+				if !merged[x] {
+					heap.Push(&h, candidate(w, x))
+				}
+			}
+			held[w] = nil
+		}
+	}
+
+	buffer := make([]float32, 0, alive*9)
+	left := make([]int, 0, alive)
+	for i, f := range faces {
+		if deadFace[i] {
+			continue
+		}
+		for _, v := range f {
+			buffer = append(buffer, pos[v].Points()...)
+		}
+		left = append(left, origins[i])
+	}
+	return buffer, left
+}
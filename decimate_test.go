@@ -0,0 +1,120 @@
+package meshview
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// gridBox returns a box with each face split into an n x n grid of quads
+func gridBox(min, max fauxgl.Vector, n int) []*fauxgl.Triangle {
+	var triangles []*fauxgl.Triangle
+	size := max.Sub(min)
+	// each face as (origin, u, v) in the unit cube, wound outward
+	faces := [][3]fauxgl.Vector{
+		{fauxgl.V(0, 0, 0), fauxgl.V(0, 1, 0), fauxgl.V(1, 0, 0)},
+		{fauxgl.V(0, 0, 1), fauxgl.V(1, 0, 0), fauxgl.V(0, 1, 0)},
+		{fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(0, 0, 1)},
+		{fauxgl.V(0, 1, 0), fauxgl.V(0, 0, 1), fauxgl.V(1, 0, 0)},
+		{fauxgl.V(0, 0, 0), fauxgl.V(0, 0, 1), fauxgl.V(0, 1, 0)},
+		{fauxgl.V(1, 0, 0), fauxgl.V(0, 1, 0), fauxgl.V(0, 0, 1)},
+	}
+	point := func(f [3]fauxgl.Vector, i, j int) fauxgl.Vector {
+		p := f[0].Add(f[1].MulScalar(float64(i) / float64(n))).Add(f[2].MulScalar(float64(j) / float64(n)))
+		return min.Add(p.Mul(size))
+	}
+	for _, f := range faces {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a, b := point(f, i, j), point(f, i+1, j)
+				c, d := point(f, i+1, j+1), point(f, i, j+1)
+				triangles = append(triangles,
+					fauxgl.NewTriangleForPoints(a, b, c),
+					fauxgl.NewTriangleForPoints(a, c, d))
+			}
+		}
+	}
+	return triangles
+}
+
+func TestDecimate(t *testing.T) {
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(gridBox(fauxgl.V(1, 2, 3), fauxgl.V(3, 4, 5), 8)))
+	decimated := Decimate(data, 12)
+	n := len(decimated.Buffer) / 9
+	if n != 12 {
+		t.Errorf("decimated to %d triangles, want 12", n)
+	}
+	// a box's flat faces lose nothing
+	if p := decimated.MassProperties(0); !nearlyRounded(p.Volume, 8) || !nearlyRounded(p.Area, 24) {
+		t.Errorf("volume %g area %g, want 8 and 24", p.Volume, p.Area)
+	}
+	if decimated.Box != data.Box {
+		t.Errorf("box %v, want %v", decimated.Box, data.Box)
+	}
+	if a := Analyze(decimated, 1e-6); !a.Watertight() {
+		t.Errorf("decimated box is not watertight")
+	}
+
+	// an open square keeps its outline
+	square := gridBox(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1), 8)[:128]
+	data = FauxMesh2MeshData(fauxgl.NewTriangleMesh(square))
+	decimated = Decimate(data, 2)
+	if p := decimated.MassProperties(0); !nearlyRounded(p.Area, 1) {
+		t.Errorf("square area %g after decimating, want 1", p.Area)
+	}
+	if decimated.Box != data.Box {
+		t.Errorf("square box %v, want %v", decimated.Box, data.Box)
+	}
+}
+
+func TestDecimateOrder(t *testing.T) {
+	// however the triangles come, collapses ruled out at first are made
+	// once the faces around them allow, rather than cutting corners
+	for seed := int64(0); seed < 20; seed++ {
+		triangles := gridBox(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2), 4)
+		rand.New(rand.NewSource(seed)).Shuffle(len(triangles), func(i, j int) {
+			triangles[i], triangles[j] = triangles[j], triangles[i]
+		})
+		decimated := Decimate(FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles)), 12)
+		if n := len(decimated.Buffer) / 9; n != 12 {
+			t.Errorf("seed %d: decimated to %d triangles, want 12", seed, n)
+		}
+		if p := decimated.MassProperties(0); !nearlyRounded(p.Volume, 8) {
+			t.Errorf("seed %d: volume %g, want 8", seed, p.Volume)
+		}
+	}
+}
+
+func TestDecimateIndexed(t *testing.T) {
+	triangles := gridBox(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2), 8)
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	decimated, origins := DecimateIndexed(data.Indexed(), 12)
+	if n := len(decimated.Buffer) / 9; n != 12 || len(origins) != n {
+		t.Fatalf("decimated to %d triangles from %d, want 12", n, len(origins))
+	}
+	// each triangle left lies on the same side of the box as the one it
+	// came from
+	for i, tri := range BufferTriangles(decimated.Buffer) {
+		n := tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0])).Normalize()
+		if want := triangles[origins[i]].Normal(); n.Sub(want).Length() > 1e-6 {
+			t.Errorf("triangle %d faces %v, its origin %d %v", i, n, origins[i], want)
+		}
+	}
+}
+
+func BenchmarkDecimate(b *testing.B) {
+	// about half a million triangles down to the default proxy size
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(gridBox(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1), 200)))
+	indexed := data.Indexed()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DecimateIndexed(indexed, LodTarget)
+	}
+}
+
+// nearlyRounded compares values that have been through float32 buffers
+func nearlyRounded(a, b float64) bool {
+	return math.Abs(a-b) < 1e-5
+}
@@ -0,0 +1,371 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Alignment chooses how one mesh is moved onto another before comparing
+type Alignment int
+
+// Meshes are compared where they are, with their bounding box centers
+// matched, or fitted by iterative closest point from there
+const (
+	AlignNone Alignment = iota
+	AlignCenters
+	AlignICP
+)
+
+// ParseAlignment parses "none", "centers" or "icp"
+func ParseAlignment(s string) (Alignment, error) {
+	switch s {
+	case "none":
+		return AlignNone, nil
+	case "centers":
+		return AlignCenters, nil
+	case "icp":
+		return AlignICP, nil
+	}
+	return AlignNone, fmt.Errorf("unknown alignment %q", s)
+}
+
+// icpIterations and icpSamples bound the work Align does for AlignICP
+const (
+	icpIterations = 50
+	icpSamples    = 5000
+)
+
+// Align returns the rigid transform that moves a onto b
+func Align(a, b *MeshData, alignment Alignment) fauxgl.Matrix {
+	if alignment == AlignNone {
+		return fauxgl.Identity()
+	}
+	m := fauxgl.Translate(b.Box.Center().Sub(a.Box.Center()))
+	if alignment == AlignCenters {
+		return m
+	}
+
+	vertices, _ := Weld(a.Buffer, WeldTolerance(a.Box))
+	step := len(vertices)/icpSamples + 1
+	var points []fauxgl.Vector
+	for i := 0; i < len(vertices); i += step {
+		points = append(points, vertices[i])
+	}
+	bvh := NewBVH(BufferTriangles(b.Buffer))
+	moved := make([]fauxgl.Vector, len(points))
+	nearest := make([]fauxgl.Vector, len(points))
+	last := math.Inf(1)
+	for iteration := 0; iteration < icpIterations; iteration++ {
+		var sum float64
+		for i, p := range points {
+			moved[i] = m.MulPosition(p)
+			_, nearest[i] = bvh.Nearest(moved[i])
+			d := nearest[i].Sub(moved[i])
+			sum += d.Dot(d)
+		}
+		rms := math.Sqrt(sum / float64(len(points)))
+		if last-rms <= WeldTolerance(b.Box) {
+			break
+		}
+		last = rms
+		m = rigidTransform(moved, nearest).Mul(m)
+	}
+	return m
+}
+
+// rigidTransform returns the rotation and translation that best moves src
+// onto dst, by Horn's quaternion method
+func rigidTransform(src, dst []fauxgl.Vector) fauxgl.Matrix {
+	var cs, cd fauxgl.Vector
+	for i := range src {
+		cs = cs.Add(src[i])
+		cd = cd.Add(dst[i])
+	}
+	cs = cs.DivScalar(float64(len(src)))
+	cd = cd.DivScalar(float64(len(dst)))
+
+	// cross covariance
+	var s [3][3]float64
+	for i := range src {
+		p := src[i].Sub(cs)
+		q := dst[i].Sub(cd)
+		a := [3]float64{p.X, p.Y, p.Z}
+		b := [3]float64{q.X, q.Y, q.Z}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				s[r][c] += a[r] * b[c]
+			}
+		}
+	}
+	n := [4][4]float64{
+		{s[0][0] + s[1][1] + s[2][2], s[1][2] - s[2][1], s[2][0] - s[0][2], s[0][1] - s[1][0]},
+		{s[1][2] - s[2][1], s[0][0] - s[1][1] - s[2][2], s[0][1] + s[1][0], s[2][0] + s[0][2]},
+		{s[2][0] - s[0][2], s[0][1] + s[1][0], -s[0][0] + s[1][1] - s[2][2], s[1][2] + s[2][1]},
+		{s[0][1] - s[1][0], s[2][0] + s[0][2], s[1][2] + s[2][1], -s[0][0] - s[1][1] + s[2][2]},
+	}
+	values, vectors := jacobiEigen(n)
+	k := 0
+	for i := range values {
+		if values[i] > values[k] {
+			k = i
+		}
+	}
+	w, x, y, z := vectors[0][k], vectors[1][k], vectors[2][k], vectors[3][k]
+
+	r := fauxgl.Matrix{
+		X00: w*w + x*x - y*y - z*z, X01: 2 * (x*y - w*z), X02: 2 * (x*z + w*y),
+		X10: 2 * (x*y + w*z), X11: w*w - x*x + y*y - z*z, X12: 2 * (y*z - w*x),
+		X20: 2 * (x*z - w*y), X21: 2 * (y*z + w*x), X22: w*w - x*x - y*y + z*z,
+		X33: 1,
+	}
+	return fauxgl.Translate(cd.Sub(r.MulPosition(cs))).Mul(r)
+}
+
+// jacobiEigen returns the eigenvalues of the symmetric matrix a and the
+// eigenvectors as the columns of a matrix
+func jacobiEigen(a [4][4]float64) ([4]float64, [4][4]float64) {
+	var v [4][4]float64
+	for i := range v {
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 50; sweep++ {
+		var off float64
+		for p := 0; p < 4; p++ {
+			for q := p + 1; q < 4; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < 4; p++ {
+			for q := p + 1; q < 4; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 4; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 4; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 4; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	return [4]float64{a[0][0], a[1][1], a[2][2], a[3][3]}, v
+}
+
+// TransformData returns a copy of data with m applied to every vertex
+func TransformData(data *MeshData, m fauxgl.Matrix) *MeshData {
+	buffer := make([]float32, 0, len(data.Buffer))
+	for _, t := range BufferTriangles(data.Buffer) {
+		for _, v := range t {
+			buffer = append(buffer, m.MulPosition(v).Points()...)
+		}
+	}
+	if len(buffer) == 0 {
+		return &MeshData{buffer, fauxgl.Box{}, nil}
+	}
+	return &MeshData{buffer, boxForData(buffer), nil}
+}
+
+// DeviationReport summarizes how far one mesh strays from another
+type DeviationReport struct {
+	Min, Max  float64 // signed, negative inside the other mesh
+	Mean      float64 // of the absolute deviation
+	RMS       float64
+	Hausdorff float64 // the larger of the one sided maximums, at vertices
+}
+
+// Scale returns the largest deviation either side of zero
+func (r DeviationReport) Scale() float64 {
+	return math.Max(-r.Min, r.Max)
+}
+
+// Print writes a readable summary of the report
+func (r DeviationReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "min       %g\n", r.Min)
+	fmt.Fprintf(w, "max       %g\n", r.Max)
+	fmt.Fprintf(w, "mean      %g\n", r.Mean)
+	fmt.Fprintf(w, "rms       %g\n", r.RMS)
+	fmt.Fprintf(w, "hausdorff %g\n", r.Hausdorff)
+}
+
+// signedDistances returns the distance from each point to the nearest face
+// of bvh, negative behind it. Corners within tol of each other are taken
+// as shared.
+func signedDistances(points []fauxgl.Vector, bvh *BVH, tol float64) []float64 {
+	distances := make([]float64, len(points))
+	wn := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for wi := 0; wi < wn; wi++ {
+		wg.Add(1)
+		go func(wi int) {
+			for i := wi; i < len(points); i += wn {
+				p := points[i]
+				j, q := bvh.Nearest(p)
+				if j < 0 {
+					distances[i] = math.Inf(1)
+					continue
+				}
+				d := p.Sub(q)
+				distances[i] = d.Length()
+				if d.Dot(pseudoNormal(bvh, j, q, tol)) < 0 {
+					distances[i] = -distances[i]
+				}
+			}
+			wg.Done()
+		}(wi)
+	}
+	wg.Wait()
+	return distances
+}
+
+// pseudoNormal returns the angle weighted pseudonormal at q, a point of
+// triangle j. Inside the triangle that is its normal, but on an edge or a
+// corner it sums the normals of every face there, each weighted by its
+// angle at q, since one face alone can put a point on the wrong side.
+func pseudoNormal(bvh *BVH, j int, q fauxgl.Vector, tol float64) fauxgl.Vector {
+	t := bvh.Triangles[j]
+	normal := func(t Triangle) fauxgl.Vector {
+		return t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Normalize()
+	}
+
+	// the corners of t that q is not on the opposite edge of
+	var on []fauxgl.Vector
+	for k, w := range barycentric(q, t) {
+		if w > pseudoNormalEpsilon {
+			on = append(on, t[k])
+		}
+	}
+	if len(on) == 3 {
+		return normal(t)
+	}
+
+	near := func(a, b fauxgl.Vector) bool {
+		return a.Sub(b).Length() <= tol
+	}
+	box := fauxgl.Box{Min: q, Max: q}.Offset(tol)
+	var sum fauxgl.Vector
+	bvh.Query(box, func(i int) {
+		u := bvh.Triangles[i]
+		n := normal(u)
+		if len(on) == 2 {
+			shares := 0
+			for _, c := range u {
+				if near(c, on[0]) || near(c, on[1]) {
+					shares++
+				}
+			}
+			if shares == 2 {
+				sum = sum.Add(n)
+			}
+			return
+		}
+		for k, c := range u {
+			if near(c, on[0]) {
+				e1 := u[(k+1)%3].Sub(c).Normalize()
+				e2 := u[(k+2)%3].Sub(c).Normalize()
+				angle := math.Acos(math.Max(-1, math.Min(1, e1.Dot(e2))))
+				sum = sum.Add(n.MulScalar(angle))
+				return
+			}
+		}
+	})
+	if sum.Length() == 0 {
+		return normal(t)
+	}
+	return sum
+}
+
+// pseudoNormalEpsilon is the barycentric weight below which a point of a
+// triangle counts as on the edge opposite that corner
+const pseudoNormalEpsilon = 1e-9
+
+// barycentric returns the weights of the corners of t that give q
+func barycentric(q fauxgl.Vector, t Triangle) [3]float64 {
+	v0, v1, v2 := t[1].Sub(t[0]), t[2].Sub(t[0]), q.Sub(t[0])
+	d00, d01, d11 := v0.Dot(v0), v0.Dot(v1), v1.Dot(v1)
+	d20, d21 := v2.Dot(v0), v2.Dot(v1)
+	denom := d00*d11 - d01*d01
+	if denom == 0 {
+		return [3]float64{1, 1, 1}
+	}
+	v := (d11*d20 - d01*d21) / denom
+	w := (d00*d21 - d01*d20) / denom
+	return [3]float64{1 - v - w, v, w}
+}
+
+// Deviation returns the signed distance from each vertex of a's buffer to
+// the nearest face of b, positive outside b, and a summary of them
+func Deviation(a, b *MeshData) ([]float64, DeviationReport) {
+	var r DeviationReport
+	va, ia := Weld(a.Buffer, WeldTolerance(a.Box))
+	vb, _ := Weld(b.Buffer, WeldTolerance(b.Box))
+	fromA := signedDistances(va, NewBVH(BufferTriangles(b.Buffer)), WeldTolerance(b.Box))
+	fromB := signedDistances(vb, NewBVH(BufferTriangles(a.Buffer)), WeldTolerance(a.Box))
+
+	if len(fromA) > 0 {
+		r.Min, r.Max = math.Inf(1), math.Inf(-1)
+	}
+	var sum, squares float64
+	for _, d := range fromA {
+		r.Min = math.Min(r.Min, d)
+		r.Max = math.Max(r.Max, d)
+		sum += math.Abs(d)
+		squares += d * d
+		r.Hausdorff = math.Max(r.Hausdorff, math.Abs(d))
+	}
+	if len(fromA) > 0 {
+		r.Mean = sum / float64(len(fromA))
+		r.RMS = math.Sqrt(squares / float64(len(fromA)))
+	}
+	for _, d := range fromB {
+		r.Hausdorff = math.Max(r.Hausdorff, math.Abs(d))
+	}
+
+	deviation := make([]float64, len(ia))
+	for i, j := range ia {
+		deviation[i] = fromA[j]
+	}
+	return deviation, r
+}
+
+// DeviationColor returns the heatmap color of deviation d: blue at -scale,
+// green at zero and red at +scale
+func DeviationColor(d, scale float64) fauxgl.Vector {
+	if scale <= 0 {
+		return Ramp(0.5)
+	}
+	return Ramp(0.5 + d/(2*scale))
+}
+
+// DeviationColors returns the per vertex color buffer for deviation
+func DeviationColors(deviation []float64, scale float64) []float32 {
+	colors := make([]float32, 0, len(deviation)*3)
+	for _, d := range deviation {
+		colors = append(colors, DeviationColor(d, scale).Points()...)
+	}
+	return colors
+}
@@ -0,0 +1,101 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestDeviation(t *testing.T) {
+	// a 2 unit cube against one a tenth larger all round
+	a := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))))
+	b := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(-0.1, -0.1, -0.1), fauxgl.V(2.1, 2.1, 2.1))))
+	deviation, r := Deviation(a, b)
+	if len(deviation) != len(a.Buffer)/3 {
+		t.Fatalf("%d deviations, want %d", len(deviation), len(a.Buffer)/3)
+	}
+	for i, d := range deviation {
+		if math.Abs(d+0.1) > 1e-6 {
+			t.Errorf("vertex %d deviates %g, want -0.1", i, d)
+		}
+	}
+	if math.Abs(r.RMS-0.1) > 1e-6 || math.Abs(r.Max+0.1) > 1e-6 {
+		t.Errorf("rms %g max %g, want 0.1 and -0.1", r.RMS, r.Max)
+	}
+	// the larger cube's corners are furthest from the smaller
+	if want := math.Sqrt(0.03); math.Abs(r.Hausdorff-want) > 1e-6 {
+		t.Errorf("hausdorff %g, want %g", r.Hausdorff, want)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	// an L shaped pair of boxes, and the same turned and moved
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(4, 1, 1))
+	triangles = append(triangles, boxTriangles(fauxgl.V(0, 1, 0), fauxgl.V(1, 3, 1))...)
+	a := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	c, s := math.Cos(0.1), math.Sin(0.1)
+	m := fauxgl.Translate(fauxgl.V(0.2, -0.1, 0.05)).Mul(fauxgl.Matrix{
+		X00: c, X01: -s, X10: s, X11: c, X22: 1, X33: 1,
+	})
+	b := TransformData(a, m)
+
+	moved := TransformData(a, Align(a, b, AlignICP))
+	_, r := Deviation(moved, b)
+	if r.Hausdorff > 1e-3 {
+		t.Errorf("hausdorff %g after icp", r.Hausdorff)
+	}
+	moved = TransformData(a, Align(a, b, AlignCenters))
+	if d := moved.Box.Center().Sub(b.Box.Center()).Length(); d > 1e-6 {
+		t.Errorf("centers %g apart after centering", d)
+	}
+}
+
+func TestRigidTransform(t *testing.T) {
+	src := []fauxgl.Vector{fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(0, 2, 0), fauxgl.V(0, 0, 3)}
+	// a quarter turn about z, then a shift
+	dst := make([]fauxgl.Vector, len(src))
+	for i, p := range src {
+		dst[i] = fauxgl.V(-p.Y+1, p.X+2, p.Z+3)
+	}
+	m := rigidTransform(src, dst)
+	for i, p := range src {
+		if d := m.MulPosition(p).Sub(dst[i]).Length(); d > 1e-9 {
+			t.Errorf("point %d is %g from its target", i, d)
+		}
+	}
+}
+
+func TestSignedDistancesCorners(t *testing.T) {
+	// a steep square pyramid, whose apex is nearest to points beside it
+	// that are behind the faces on the far side
+	apex := fauxgl.V(0, 0, 4)
+	base := []fauxgl.Vector{fauxgl.V(-1, -1, 0), fauxgl.V(1, -1, 0), fauxgl.V(1, 1, 0), fauxgl.V(-1, 1, 0)}
+	triangles := []*fauxgl.Triangle{
+		fauxgl.NewTriangleForPoints(base[0], base[2], base[1]),
+		fauxgl.NewTriangleForPoints(base[0], base[3], base[2]),
+	}
+	for i, a := range base {
+		triangles = append(triangles, fauxgl.NewTriangleForPoints(a, base[(i+1)%4], apex))
+	}
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	inside := func(p fauxgl.Vector) bool {
+		r := 1 - p.Z/4
+		return p.Z > 0 && math.Abs(p.X) < r && math.Abs(p.Y) < r
+	}
+
+	var points []fauxgl.Vector
+	for x := -2; x <= 2; x++ {
+		for y := -2; y <= 2; y++ {
+			for z := -2; z <= 2; z++ {
+				points = append(points, apex.Add(fauxgl.V(float64(x), float64(y), float64(z)).MulScalar(0.1)))
+			}
+		}
+	}
+	distances := signedDistances(points, NewBVH(BufferTriangles(data.Buffer)), 1e-9)
+	for i, p := range points {
+		if distances[i] != 0 && (distances[i] < 0) != inside(p) {
+			t.Errorf("%v: distance %g on the wrong side", p, distances[i])
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package meshview
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fogleman/slicer"
+)
+
+// SaveLayers writes layers to path in the format given by its extension
+func SaveLayers(path string, layers []slicer.Layer) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	if err := WriteLayers(w, filepath.Ext(path), layers); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// WriteLayers writes layers to w as svg, dxf or json
+func WriteLayers(w io.Writer, format string, layers []slicer.Layer) error {
+	switch strings.TrimPrefix(strings.ToLower(format), ".") {
+	case "svg":
+		return WriteSVG(w, layers)
+	case "dxf":
+		return WriteDXF(w, layers)
+	case "json":
+		return WriteJSON(w, layers)
+	}
+	return fmt.Errorf("unrecognized slice format: %s", format)
+}
+
+// layerBounds returns the 2D extent of the paths in layers
+func layerBounds(layers []slicer.Layer) (x0, y0, x1, y1 float64) {
+	x0, y0 = math.Inf(1), math.Inf(1)
+	x1, y1 = math.Inf(-1), math.Inf(-1)
+	for _, layer := range layers {
+		for _, path := range layer.Paths {
+			for _, v := range path {
+				x0 = math.Min(x0, v.X)
+				y0 = math.Min(y0, v.Y)
+				x1 = math.Max(x1, v.X)
+				y1 = math.Max(y1, v.Y)
+			}
+		}
+	}
+	if x0 > x1 {
+		return 0, 0, 0, 0
+	}
+	return
+}
+
+// WriteSVG writes layers as one svg in millimeters, each layer a group of
+// closed paths. Y is flipped so the drawing is viewed from above.
+func WriteSVG(w io.Writer, layers []slicer.Layer) error {
+	x0, y0, x1, y1 := layerBounds(layers)
+	width, height := x1-x0, y1-y0
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%gmm\" height=\"%gmm\" viewBox=\"%g %g %g %g\">\n",
+		width, height, x0, -y1, width, height)
+	for i, layer := range layers {
+		fmt.Fprintf(w, "<g id=\"layer%d\" data-z=\"%g\" fill=\"none\" stroke=\"black\" stroke-width=\"0.1\">\n", i, layer.Z)
+		for _, path := range layer.Paths {
+			if len(path) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "<path d=\"M%g,%g", path[0].X, -path[0].Y)
+			for _, v := range path[1:] {
+				fmt.Fprintf(w, " L%g,%g", v.X, -v.Y)
+			}
+			fmt.Fprintf(w, " Z\"/>\n")
+		}
+		fmt.Fprintf(w, "</g>\n")
+	}
+	_, err := fmt.Fprintf(w, "</svg>\n")
+	return err
+}
+
+// WriteDXF writes layers as R12 dxf, one closed polyline per path on a dxf
+// layer per slice, at the slice's elevation
+func WriteDXF(w io.Writer, layers []slicer.Layer) error {
+	pair := func(code int, value interface{}) {
+		fmt.Fprintf(w, "%d\n%v\n", code, value)
+	}
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	for i, layer := range layers {
+		name := fmt.Sprintf("LAYER%d", i)
+		for _, path := range layer.Paths {
+			pair(0, "POLYLINE")
+			pair(8, name)
+			pair(66, 1)
+			pair(70, 1)
+			pair(10, 0.0)
+			pair(20, 0.0)
+			pair(30, layer.Z)
+			for _, v := range path {
+				pair(0, "VERTEX")
+				pair(8, name)
+				pair(10, v.X)
+				pair(20, v.Y)
+				pair(30, layer.Z)
+			}
+			pair(0, "SEQEND")
+			pair(8, name)
+		}
+	}
+	pair(0, "ENDSEC")
+	_, err := fmt.Fprintf(w, "0\nEOF\n")
+	return err
+}
+
+// jsonLayer is the json form of a layer: its z and 2D closed paths
+type jsonLayer struct {
+	Z     float64        `json:"z"`
+	Paths [][][2]float64 `json:"paths"`
+}
+
+// WriteJSON writes layers as a json array of {"z", "paths"} objects, each
+// path an array of [x, y] points
+func WriteJSON(w io.Writer, layers []slicer.Layer) error {
+	out := make([]jsonLayer, len(layers))
+	for i, layer := range layers {
+		out[i].Z = layer.Z
+		out[i].Paths = make([][][2]float64, len(layer.Paths))
+		for j, path := range layer.Paths {
+			points := make([][2]float64, len(path))
+			for k, v := range path {
+				points[k] = [2]float64{v.X, v.Y}
+			}
+			out[i].Paths[j] = points
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,52 @@
+package meshview
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+func testLayers() []slicer.Layer {
+	square := slicer.Path{
+		fauxgl.V(0, 0, 1), fauxgl.V(1, 0, 1), fauxgl.V(1, 1, 1), fauxgl.V(0, 1, 1), fauxgl.V(0, 0, 1),
+	}
+	return []slicer.Layer{{Z: 1, Paths: []slicer.Path{square}}}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testLayers()); err != nil {
+		t.Fatal(err)
+	}
+	var layers []struct {
+		Z     float64
+		Paths [][][2]float64
+	}
+	if err := json.Unmarshal(buf.Bytes(), &layers); err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].Z != 1 || len(layers[0].Paths[0]) != 5 {
+		t.Errorf("bad json %s", buf.String())
+	}
+}
+
+func TestWriteLayers(t *testing.T) {
+	for _, format := range []string{"svg", ".DXF", "json"} {
+		var buf bytes.Buffer
+		if err := WriteLayers(&buf, format, testLayers()); err != nil || buf.Len() == 0 {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteLayers(&buf, "png", testLayers()); err == nil {
+		t.Errorf("expected error for png")
+	}
+	WriteDXF(&buf, testLayers())
+	if strings.Count(buf.String(), "VERTEX") != 5 {
+		t.Errorf("bad dxf vertex count")
+	}
+}
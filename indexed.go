@@ -0,0 +1,91 @@
+package meshview
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// IndexedMeshData is a mesh with each distinct vertex stored once, as x, y,
+// z floats, and triangles as triples of indices into them
+type IndexedMeshData struct {
+	Vertices []float32
+	Indices  []uint32
+	Box      fauxgl.Box
+}
+
+// NewIndexedMeshData makes an IndexedMeshData, computing its box
+func NewIndexedMeshData(vertices []float32, indices []uint32) *IndexedMeshData {
+	if len(vertices) == 0 {
+		return &IndexedMeshData{vertices, indices, fauxgl.Box{}}
+	}
+	return &IndexedMeshData{vertices, indices, boxForData(vertices)}
+}
+
+// IndexBuffer welds the vertices of a de-indexed buffer that are exactly
+// equal, as the corners shared between triangles of an stl file are,
+// returning the distinct vertices and an index for each corner
+func IndexBuffer(buffer []float32) ([]float32, []uint32) {
+	lookup := make(map[[3]float32]uint32, len(buffer)/9)
+	var vertices []float32
+	indices := make([]uint32, len(buffer)/3)
+	for i := range indices {
+		k := [3]float32{buffer[i*3], buffer[i*3+1], buffer[i*3+2]}
+		index, ok := lookup[k]
+		if !ok {
+			index = uint32(len(vertices) / 3)
+			lookup[k] = index
+			vertices = append(vertices, k[:]...)
+		}
+		indices[i] = index
+	}
+	return vertices, indices
+}
+
+// Indexed returns data with its shared vertices welded
+func (data *MeshData) Indexed() *IndexedMeshData {
+	vertices, indices := IndexBuffer(data.Buffer)
+	return &IndexedMeshData{vertices, indices, data.Box}
+}
+
+// MeshData expands the mesh into a de-indexed buffer
+func (m *IndexedMeshData) MeshData() *MeshData {
+	buffer := make([]float32, len(m.Indices)*3)
+	for i, index := range m.Indices {
+		copy(buffer[i*3:i*3+3], m.Vertices[index*3:index*3+3])
+	}
+	return &MeshData{buffer, m.Box, nil}
+}
+
+// Vertex returns vertex i
+func (m *IndexedMeshData) Vertex(i uint32) fauxgl.Vector {
+	v := m.Vertices[i*3 : i*3+3]
+	return fauxgl.V(float64(v[0]), float64(v[1]), float64(v[2]))
+}
+
+// Neighbors returns, for each vertex, the triangles using it
+func (m *IndexedMeshData) Neighbors() [][]int {
+	neighbors := make([][]int, len(m.Vertices)/3)
+	for i, index := range m.Indices {
+		neighbors[index] = append(neighbors[index], i/3)
+	}
+	return neighbors
+}
+
+// LoadIndexedMesh loads an stl or obj file as an indexed mesh
+func LoadIndexedMesh(path string) (*IndexedMeshData, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".stl":
+		data, err := LoadSTL(path)
+		if err != nil {
+			return nil, err
+		}
+		return data.Indexed(), nil
+	case ".obj":
+		return LoadIndexedOBJ(path)
+	}
+	return nil, fmt.Errorf("unrecognized mesh extension: %s", ext)
+}
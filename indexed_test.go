@@ -0,0 +1,57 @@
+package meshview
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestIndexed(t *testing.T) {
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 2, 3))))
+	indexed := data.Indexed()
+	if len(indexed.Vertices) != 8*3 || len(indexed.Indices) != 36 {
+		t.Fatalf("%d vertices and %d indices, want 8 and 36", len(indexed.Vertices)/3, len(indexed.Indices))
+	}
+	expanded := indexed.MeshData()
+	for i := range data.Buffer {
+		if expanded.Buffer[i] != data.Buffer[i] {
+			t.Fatalf("expanded buffer differs at %d", i)
+		}
+	}
+	for v, triangles := range indexed.Neighbors() {
+		// each corner of a box is on three faces, split into 4 to 6 triangles
+		if len(triangles) < 3 || len(triangles) > 6 {
+			t.Errorf("vertex %d on %d triangles", v, len(triangles))
+		}
+	}
+}
+
+func TestLoadIndexedOBJ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quad.obj")
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf 1 2 3 -1\n"
+	if err := os.WriteFile(path, []byte(obj), 0644); err != nil {
+		t.Fatal(err)
+	}
+	indexed, err := LoadIndexedOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{0, 1, 2, 0, 2, 3}
+	if len(indexed.Indices) != len(want) {
+		t.Fatalf("indices %v, want %v", indexed.Indices, want)
+	}
+	for i := range want {
+		if indexed.Indices[i] != want[i] {
+			t.Fatalf("indices %v, want %v", indexed.Indices, want)
+		}
+	}
+	data, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Buffer) != 18 || data.Box.Max != fauxgl.V(1, 1, 0) {
+		t.Errorf("obj buffer %v box %v", data.Buffer, data.Box)
+	}
+}
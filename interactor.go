@@ -170,20 +170,21 @@ func (a *Arcball) KeyCallback(window *glfw.Window, key glfw.Key, scancode int, a
 			a.Rotation = a.Rotation.Rotate(fauxgl.V(0,0,1), -math.Pi/60)
 		case glfw.KeyRight:
 			a.Rotation = a.Rotation.Rotate(fauxgl.V(0,0,1), math.Pi/60)
-		case glfw.KeyUp:
-			if sliceIndex < sliceMax {
-				sliceIndex++
-				lastMatrix = fauxgl.Matrix{}
-			}
-		case glfw.KeyDown:
-			if sliceIndex > 0 {
-				sliceIndex--
-				lastMatrix = fauxgl.Matrix{}
-			}
 		}
 	}
 }
 
+// Frame zooms and pans so that a sphere at center with radius, both in
+// model space, fills the middle of the view
+func (a *Arcball) Frame(center fauxgl.Vector, radius float64) {
+	s := 1.0
+	if radius > 0 {
+		s = math.Max(1, 0.5/radius)
+	}
+	a.Scroll = math.Log(s) / math.Log(0.98)
+	a.Translation = a.Rotation.MulPosition(center.MulScalar(s)).Negate()
+}
+
 // ScrollCallback (MGD)
 func (a *Arcball) ScrollCallback(window *glfw.Window, dx, dy float64) {
 	a.Scroll += dy
@@ -192,7 +193,12 @@ func (a *Arcball) ScrollCallback(window *glfw.Window, dx, dy float64) {
 // Matrix (MGD)
 func (a *Arcball) Matrix(window *glfw.Window) fauxgl.Matrix {
 	w, h := window.GetFramebufferSize()
-	aspect := float64(w) / float64(h)
+	return a.Project(float64(w) / float64(h))
+}
+
+// Project returns the view, as Matrix does, projected for a viewport of
+// the given aspect ratio
+func (a *Arcball) Project(aspect float64) fauxgl.Matrix {
 	r := a.Rotation
 	if a.Rotate {
 		r = arcballRotate(a.Start, a.Current, a.Sensitivity).Mul(r)
@@ -0,0 +1,107 @@
+package meshview
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// intersectEpsilon keeps triangles that merely touch at an edge or corner
+// from counting as intersecting
+const intersectEpsilon = 1e-9
+
+// segmentHits reports whether the segment from p to q passes through the
+// interior of triangle t
+func segmentHits(p, q fauxgl.Vector, t Triangle) bool {
+	d := q.Sub(p)
+	e1 := t[1].Sub(t[0])
+	e2 := t[2].Sub(t[0])
+	h := d.Cross(e2)
+	a := e1.Dot(h)
+	if a > -intersectEpsilon && a < intersectEpsilon {
+		return false // parallel
+	}
+	f := 1 / a
+	s := p.Sub(t[0])
+	u := f * s.Dot(h)
+	if u <= intersectEpsilon || u >= 1-intersectEpsilon {
+		return false
+	}
+	r := s.Cross(e1)
+	v := f * d.Dot(r)
+	if v <= intersectEpsilon || u+v >= 1-intersectEpsilon {
+		return false
+	}
+	w := f * e2.Dot(r)
+	return w > intersectEpsilon && w < 1-intersectEpsilon
+}
+
+// TrianglesIntersect reports whether an edge of either triangle passes
+// through the other. Coplanar overlaps are not detected.
+func TrianglesIntersect(a, b Triangle) bool {
+	for i := 0; i < 3; i++ {
+		if segmentHits(a[i], a[(i+1)%3], b) || segmentHits(b[i], b[(i+1)%3], a) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharesCorner reports whether two triangles have a corner in common, as
+// neighbors in a mesh do
+func sharesCorner(a, b Triangle) bool {
+	for _, p := range a {
+		for _, q := range b {
+			if p == q {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SelfIntersections returns the index pairs of triangles in data that pass
+// through one another, ignoring neighbors that share a corner. Pairs are
+// ordered, lower index first.
+func SelfIntersections(data *MeshData) [][2]int {
+	triangles := BufferTriangles(data.Buffer)
+	bvh := NewBVH(triangles)
+
+	count := len(triangles)
+	wn := runtime.NumCPU()
+	results := make([][][2]int, wn)
+	var wg sync.WaitGroup
+	for wi := 0; wi < wn; wi++ {
+		wg.Add(1)
+		go func(wi int) {
+			for i := wi; i < count; i += wn {
+				a := triangles[i]
+				bvh.Query(a.Box(), func(j int) {
+					if j <= i {
+						return
+					}
+					b := triangles[j]
+					if !sharesCorner(a, b) && TrianglesIntersect(a, b) {
+						results[wi] = append(results[wi], [2]int{i, j})
+					}
+				})
+			}
+			wg.Done()
+		}(wi)
+	}
+	wg.Wait()
+
+	var pairs [][2]int
+	for _, r := range results {
+		pairs = append(pairs, r...)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
@@ -0,0 +1,29 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestSelfIntersections(t *testing.T) {
+	// two boxes, the second pushed through a face of the first
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))
+	n := len(triangles)
+	triangles = append(triangles, boxTriangles(fauxgl.V(0.5, 0.5, 1), fauxgl.V(1.5, 1.5, 3))...)
+	pairs := SelfIntersections(FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles)))
+	if len(pairs) == 0 {
+		t.Fatalf("found no intersections")
+	}
+	for _, p := range pairs {
+		if p[0] >= n || p[1] < n {
+			t.Errorf("pair %v is within one box", p)
+		}
+	}
+
+	// a closed box alone doesn't intersect itself
+	single := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1))))
+	if pairs := SelfIntersections(single); len(pairs) != 0 {
+		t.Errorf("box intersects itself: %v", pairs)
+	}
+}
@@ -0,0 +1,91 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// MassProperties are the integral properties of a closed mesh. They assume
+// outward facing triangles; a mesh wound inside out has negative volume.
+type MassProperties struct {
+	Volume   float64       `json:"volume"`
+	Area     float64       `json:"area"`
+	Centroid fauxgl.Vector `json:"centroid"`
+	Inertia  [3][3]float64 `json:"inertia"` // about the centroid
+	Density  float64       `json:"density"`
+	Mass     float64       `json:"mass"`
+}
+
+// MassProperties integrates the volume, surface area, center of mass and
+// inertia tensor of the mesh. The inertia and mass scale with density; if
+// it is zero there is no mass and the inertia is for unit density.
+func (data *MeshData) MassProperties(density float64) MassProperties {
+	p := MassProperties{Density: density}
+	var c [3][3]float64 // second moment about the origin
+	var first fauxgl.Vector
+	b := data.Buffer
+	for i := 0; i+8 < len(b); i += 9 {
+		v1 := fauxgl.V(float64(b[i]), float64(b[i+1]), float64(b[i+2]))
+		v2 := fauxgl.V(float64(b[i+3]), float64(b[i+4]), float64(b[i+5]))
+		v3 := fauxgl.V(float64(b[i+6]), float64(b[i+7]), float64(b[i+8]))
+		p.Area += v2.Sub(v1).Cross(v3.Sub(v1)).Length() / 2
+
+		// signed tetrahedron from the origin to the triangle
+		v := v1.Dot(v2.Cross(v3)) / 6
+		s := v1.Add(v2).Add(v3)
+		p.Volume += v
+		first = first.Add(s.MulScalar(v / 4))
+		for _, u := range []fauxgl.Vector{v1, v2, v3, s} {
+			w := [3]float64{u.X, u.Y, u.Z}
+			for j := 0; j < 3; j++ {
+				for k := 0; k < 3; k++ {
+					c[j][k] += v / 20 * w[j] * w[k]
+				}
+			}
+		}
+	}
+	if p.Volume == 0 {
+		return p
+	}
+	p.Centroid = first.DivScalar(p.Volume)
+
+	// move the second moment to the centroid and convert it to inertia
+	g := [3]float64{p.Centroid.X, p.Centroid.Y, p.Centroid.Z}
+	for j := 0; j < 3; j++ {
+		for k := 0; k < 3; k++ {
+			c[j][k] -= p.Volume * g[j] * g[k]
+		}
+	}
+	if density == 0 {
+		density = 1
+	}
+	trace := c[0][0] + c[1][1] + c[2][2]
+	for j := 0; j < 3; j++ {
+		for k := 0; k < 3; k++ {
+			p.Inertia[j][k] = -c[j][k] * density
+		}
+		p.Inertia[j][j] += trace * density
+	}
+	p.Mass = p.Volume * p.Density
+	return p
+}
+
+// Print writes a readable summary of the properties
+func (p MassProperties) Print(w io.Writer) {
+	fmt.Fprintf(w, "volume    %g\n", p.Volume)
+	fmt.Fprintf(w, "area      %g\n", p.Area)
+	fmt.Fprintf(w, "centroid  %g %g %g\n", p.Centroid.X, p.Centroid.Y, p.Centroid.Z)
+	if p.Density != 0 {
+		fmt.Fprintf(w, "density   %g\n", p.Density)
+		fmt.Fprintf(w, "mass      %g\n", p.Mass)
+	}
+	for j, row := range p.Inertia {
+		label := "inertia"
+		if j > 0 {
+			label = ""
+		}
+		fmt.Fprintf(w, "%-9s %g %g %g\n", label, row[0], row[1], row[2])
+	}
+}
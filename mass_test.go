@@ -0,0 +1,64 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// boxTriangles returns the outward wound faces of an axis aligned box
+func boxTriangles(min, max fauxgl.Vector) []*fauxgl.Triangle {
+	v := func(x, y, z int) fauxgl.Vector {
+		p := [2]fauxgl.Vector{min, max}
+		return fauxgl.V(p[x].X, p[y].Y, p[z].Z)
+	}
+	quad := func(a, b, c, d fauxgl.Vector) []*fauxgl.Triangle {
+		return []*fauxgl.Triangle{
+			fauxgl.NewTriangleForPoints(a, b, c),
+			fauxgl.NewTriangleForPoints(a, c, d),
+		}
+	}
+	var triangles []*fauxgl.Triangle
+	triangles = append(triangles, quad(v(0, 0, 0), v(0, 1, 0), v(1, 1, 0), v(1, 0, 0))...)
+	triangles = append(triangles, quad(v(0, 0, 1), v(1, 0, 1), v(1, 1, 1), v(0, 1, 1))...)
+	triangles = append(triangles, quad(v(0, 0, 0), v(1, 0, 0), v(1, 0, 1), v(0, 0, 1))...)
+	triangles = append(triangles, quad(v(0, 1, 0), v(0, 1, 1), v(1, 1, 1), v(1, 1, 0))...)
+	triangles = append(triangles, quad(v(0, 0, 0), v(0, 0, 1), v(0, 1, 1), v(0, 1, 0))...)
+	triangles = append(triangles, quad(v(1, 0, 0), v(1, 1, 0), v(1, 1, 1), v(1, 0, 1))...)
+	return triangles
+}
+
+func nearly(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMassProperties(t *testing.T) {
+	// a 2 x 4 x 6 box away from the origin
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(1, 2, 3), fauxgl.V(3, 6, 9))))
+	p := data.MassProperties(0.5)
+	if !nearly(p.Volume, 48) {
+		t.Errorf("volume %g, want 48", p.Volume)
+	}
+	if !nearly(p.Area, 2*(8+12+24)) {
+		t.Errorf("area %g, want 88", p.Area)
+	}
+	if p.Centroid.Sub(fauxgl.V(2, 4, 6)).Length() > 1e-9 {
+		t.Errorf("centroid %v", p.Centroid)
+	}
+	if !nearly(p.Mass, 24) {
+		t.Errorf("mass %g, want 24", p.Mass)
+	}
+	// m/12 (b^2 + c^2) about each axis
+	want := [3]float64{24.0 / 12 * (16 + 36), 24.0 / 12 * (4 + 36), 24.0 / 12 * (4 + 16)}
+	for i := 0; i < 3; i++ {
+		if !nearly(p.Inertia[i][i], want[i]) {
+			t.Errorf("inertia %d is %g, want %g", i, p.Inertia[i][i], want[i])
+		}
+		for j := 0; j < 3; j++ {
+			if i != j && !nearly(p.Inertia[i][j], 0) {
+				t.Errorf("product of inertia %d %d is %g", i, j, p.Inertia[i][j])
+			}
+		}
+	}
+}
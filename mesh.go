@@ -1,6 +1,7 @@
 package meshview
 
 import (
+	"context"
 	"log"
 
 	"github.com/fogleman/fauxgl"
@@ -29,10 +30,27 @@ func Vectors2Vao(vectors []fauxgl.Vector) Vao {
 // }
 
 
-// Vao is a buffered vertex array with length
+// Vao is a buffered vertex array with length; Buf names the vertex array
+// and Vertices the buffer it reads, and indexed vaos draw their Elements
+// buffer, with Len three times the index count
 type Vao struct {
-	Buf uint32
-	Len int32
+	Buf      uint32
+	Len      int32
+	Vertices uint32
+	Elements uint32
+}
+
+// Delete frees the vertex array and its buffers
+func (vao Vao) Delete() {
+	if vao.Vertices != 0 {
+		gl.DeleteBuffers(1, &vao.Vertices)
+	}
+	if vao.Elements != 0 {
+		gl.DeleteBuffers(1, &vao.Elements)
+	}
+	if vao.Buf != 0 {
+		gl.DeleteVertexArrays(1, &vao.Buf)
+	}
 }
 
 // NewVao makes a Vao from a []float32
@@ -47,32 +65,93 @@ func NewVao(buffer []float32) Vao {
 	gl.BindVertexArray(vao)
 	gl.EnableVertexAttribArray(0)
 	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 0, nil)
-	return Vao{vao, int32(len(buffer))}
+	return Vao{Buf: vao, Len: int32(len(buffer)), Vertices: vbo}
+}
+
+// NewIndexedVao makes a Vao drawn with glDrawElements from distinct
+// vertices and triangle indices into them
+func NewIndexedVao(vertices []float32, indices []uint32) Vao {
+	var vbo uint32
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 0, nil)
+	var ebo uint32
+	gl.GenBuffers(1, &ebo)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*4, gl.Ptr(indices), gl.STATIC_DRAW)
+	gl.BindVertexArray(0)
+	return Vao{Buf: vao, Len: int32(len(indices) * 3), Vertices: vbo, Elements: ebo}
+}
+
+// Count returns the number of vertices in the vao; Len counts floats
+func (vao Vao) Count() int32 {
+	return vao.Len / 3
 }
 
 // Draw draws a vao as triangles
 func (vao Vao) Draw() {
 	gl.BindVertexArray(vao.Buf)
-	gl.DrawArrays(gl.TRIANGLES, 0, vao.Len)
+	if vao.Elements != 0 {
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, vao.Elements)
+		gl.DrawElements(gl.TRIANGLES, vao.Count(), gl.UNSIGNED_INT, nil)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
+	} else {
+		gl.DrawArrays(gl.TRIANGLES, 0, vao.Count())
+	}
+	gl.BindVertexArray(0)
+}
+
+// DrawRange draws count vertices of a vao as triangles, from first
+func (vao Vao) DrawRange(first, count int32) {
+	gl.BindVertexArray(vao.Buf)
+	gl.DrawArrays(gl.TRIANGLES, first, count)
+	gl.BindVertexArray(0)
+}
+
+// DrawColors draws a de-indexed vao as triangles, colored per vertex from
+// the colors vbo through attrib
+func (vao Vao) DrawColors(colors, attrib uint32) {
+	gl.BindVertexArray(vao.Buf)
+	gl.BindBuffer(gl.ARRAY_BUFFER, colors)
+	gl.EnableVertexAttribArray(attrib)
+	gl.VertexAttribPointer(attrib, 3, gl.FLOAT, false, 0, nil)
+	gl.DrawArrays(gl.TRIANGLES, 0, vao.Count())
+	gl.DisableVertexAttribArray(attrib)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 	gl.BindVertexArray(0)
 }
 
+// NewColorBuffer uploads per vertex colors, returning the vbo
+func NewColorBuffer(colors []float32) uint32 {
+	var vbo uint32
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(colors)*4, gl.Ptr(colors), gl.STATIC_DRAW)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	return vbo
+}
+
 // DrawPolygon draws a vao as a polygon
 func (vao Vao) DrawPolygon() {
 	gl.BindVertexArray(vao.Buf)
-	gl.DrawArrays(gl.POLYGON, 0, vao.Len)
+	gl.DrawArrays(gl.POLYGON, 0, vao.Count())
 }
 
 // DrawLines draws a vao as lines
 func (vao Vao) DrawLines() {
 	gl.BindVertexArray(vao.Buf)
-	gl.DrawArrays(gl.LINES, 0, vao.Len)
+	gl.DrawArrays(gl.LINES, 0, vao.Count())
 }
 
 // DrawLineStrip draws a vao as linestrip
 func (vao Vao) DrawLineStrip() {
 	gl.BindVertexArray(vao.Buf)
-	gl.DrawArrays(gl.LINE_STRIP, 0, vao.Len)
+	gl.DrawArrays(gl.LINE_STRIP, 0, vao.Count())
 }
 
 // Triangles2Vao converts triangles to a Vao
@@ -87,65 +166,341 @@ func Triangles2Vao(triangles []*fauxgl.Triangle) Vao {
 
 // Model contains the mesh plus vaos and view data
 type Model struct {
-	Mesh       *fauxgl.Mesh
-	Slices     []slicer.Layer
-	Transform  fauxgl.Matrix
-	MeshVao    Vao
-	SliceVaos  [][]Vao
+	Path        string
+	// Data is the mesh as a de-indexed triangle buffer, which slicing and
+	// the analyses read
+	Data        *MeshData
+	// Indexed is the mesh with shared vertices welded, as uploaded
+	Indexed     *IndexedMeshData
+	LayerHeight float64
+	Tolerance   float64
+	Plane       Plane
+	// SliceRun counts the times SetPlane emptied the Slices, to tell the
+	// layers of this run from those of a cancelled one still arriving
+	SliceRun    int
+	Slices      []slicer.Layer
+	Contours    [][]*Contour
+	Stats       []LayerStats
+	Repairs     []LayerRepair
+	Analysis    *Analysis
+	Mass        MassProperties
+	// Intersections are pairs of indices into Mesh.Triangles, nil until
+	// found; Intersection is the pair being looked at
+	Intersections [][2]int
+	Intersection  int
+	// Overhangs classify each triangle, supported by OverhangArea
+	Overhangs    []Overhang
+	OverhangArea float64
+	// Thickness is the wall thickness of each triangle, nil until measured,
+	// and Thin summarizes the walls below the minimum
+	Thickness    []float64
+	Thin         ThicknessReport
+	// Shells are the connected parts of the mesh, drawn from ShellVao
+	// grouped in order; Shell is the one selected
+	Shells       []Shell
+	Shell        int
+	Hidden       []bool
+	ShellVao     Vao
+	// Deviation is the signed distance of each vertex from the mesh at
+	// ComparePath, nil unless comparing, and Compare summarizes it;
+	// DeviationLimit is the deviation shaded fully red or blue, the largest
+	// if zero
+	ComparePath    string
+	Deviation      []float64
+	Compare        DeviationReport
+	DeviationLimit float64
+	// Shade is the mode ShadeBuf holds per vertex colors for; ShadeBufs
+	// hold those of other modes viewports show the model in
+	Shade       ShadeMode
+	ShadeBuf    uint32
+	ShadeBufs   map[ShadeMode]uint32
+	Sliced      int
+	Transform   fauxgl.Matrix
+	// Placement moves the model into its scene, before Transform fits the
+	// scene to the view; Color is its plain color, meshColor if zero, and
+	// hidden models are not drawn
+	Placement   fauxgl.Matrix
+	Color       fauxgl.Vector
+	Visible     bool
+	// Chunks split the indexed mesh for culling, uploaded a few at a time
+	// into ChunkVaos, after which only their boxes are kept
+	Chunks      []Chunk
+	ChunkVaos   []Vao
+	// Preview holds the triangles shown while the model loaded, drawn in
+	// its place until the chunks are all uploaded
+	Preview     []Vao
+	// ShadeVao holds the mesh de-indexed, for per face ShadeBuf colors
+	ShadeVao    Vao
+	// ProxyVao is a decimated copy of the mesh drawn while the view moves,
+	// empty if the mesh is small enough to draw whole; ProxyFaces are the
+	// mesh triangles its own came from, and ProxyShadeBufs its colors for
+	// each shade buffer
+	ProxyVao       Vao
+	ProxyFaces     []int
+	ProxyShadeBufs map[uint32]uint32
+	SliceVaos   [][]Vao
+	// CacheKey names the model's entry in the cache it was loaded from, empty
+	// if none, and Cached holds the layers last read from or written to it
+	CacheKey    string
+	Cached      *CachedSlices
 	// BoxVao     Vao
 }
 
-// NewModel makes a Model from a Mesh
-func NewModel(mesh *fauxgl.Mesh) *Model {
-	r := Model{}
-	r.Mesh = mesh
-	box := mesh.BoundingBox()
+// SliceCount is the number of layers a model is sliced into
+const SliceCount = 250
 
-	// compute transform to scale and center mesh
+// BoxTransform returns the transform scaling and centering box to fit the
+// view
+func BoxTransform(box fauxgl.Box) fauxgl.Matrix {
 	scale := fauxgl.V(2, 2, 2).Div(box.Size()).MinComponent()
-	r.Transform = fauxgl.Identity()
-	r.Transform = r.Transform.Translate(box.Center().Negate())
-	r.Transform = r.Transform.Scale(fauxgl.V(scale, scale, scale))
-
-	// make 10 slices
-	r.Slices = slicer.SliceMesh(r.Mesh, (box.Max.Z-box.Min.Z)/250)
-	
-	// cleanup
-	for _, slice := range r.Slices {
-		for _, path := range slice.Paths {
-			for _, v := range path {
-				if v.Z != slice.Z {
-					log.Println("slice", slice.Z, "bad point", v)
-				}
-			}
-			if path[0] != path[len(path)-1] {
-				log.Println("slice", slice.Z, "has unclosed path", path[0], path[len(path)-1])
-			}
+	transform := fauxgl.Identity()
+	transform = transform.Translate(box.Center().Negate())
+	transform = transform.Scale(fauxgl.V(scale, scale, scale))
+	return transform
+}
+
+// NewModel makes a Model from mesh data, sliced along Z through its
+// center; the Slices hold only their Z until filled in by SliceModel
+func NewModel(data *MeshData) *Model {
+	r := Model{}
+	r.Data = data
+	box := data.Box
+
+	r.Transform = BoxTransform(box)
+	r.Placement = fauxgl.Identity()
+	r.Visible = true
+
+	// join slice path ends closer than this
+	r.Tolerance = box.Size().Length() * 1e-5
+
+	r.SetPlane(Plane{box.Center(), PlaneZ.Normal})
+	return &r
+}
+
+// SetPlane replaces the Slices with empty layers parallel to plane,
+// LayerHeight apart or SliceCount in all if LayerHeight is zero
+func (model *Model) SetPlane(plane Plane) {
+	model.Plane = plane
+	model.Slices = nil
+	model.Sliced = 0
+	model.SliceRun++
+	levels := plane.Levels(model.Data.Buffer, SliceCount)
+	if model.LayerHeight > 0 {
+		levels = plane.LevelsStep(model.Data.Buffer, model.LayerHeight)
+	}
+	for _, z := range levels {
+		model.Slices = append(model.Slices, slicer.Layer{Z: z})
+	}
+	model.Contours = make([][]*Contour, len(model.Slices))
+	model.Stats = make([]LayerStats, len(model.Slices))
+	model.Repairs = make([]LayerRepair, len(model.Slices))
+
+	// cached layers along the same plane at the same levels are reused
+	if c := model.Cached; c != nil && c.Plane == plane && sameLevels(c.Layers, levels) {
+		for i, layer := range c.Layers {
+			contours := NestPaths(layer.Paths)
+			model.AddSlice(SliceResult{model, plane, model.SliceRun, i, layer, contours, NewLayerStats(contours), c.Repairs[i]})
 		}
 	}
+}
 
-	return &r
+// AddSlice stores a finished layer
+func (model *Model) AddSlice(r SliceResult) {
+	model.Slices[r.Index] = r.Layer
+	model.Contours[r.Index] = r.Contours
+	model.Stats[r.Index] = r.Stats
+	model.Repairs[r.Index] = r.Repair
+	model.Sliced++
+}
+
+// SliceWait slices the model, unless its layers were cached, and waits
+// for every layer to finish, then writes them to the cache
+func (model *Model) SliceWait() {
+	if model.Sliced < len(model.Slices) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := make(chan SliceResult)
+		SliceModel(ctx, model, ch)
+		for model.Sliced < len(model.Slices) {
+			model.AddSlice(<-ch)
+		}
+	}
+	if c := model.UncachedSlices(); c != nil {
+		model.Cached = c
+		if err := ModelCache.Save(model.CacheKey, model.Data, c); err != nil {
+			log.Println("cache error", err)
+		}
+	}
+}
+
+// UncachedSlices returns the model's layers to write to its cache entry,
+// or nil if they are unfinished, already there or it has no entry
+func (model *Model) UncachedSlices() *CachedSlices {
+	if model.CacheKey == "" || len(model.Slices) == 0 || model.Sliced < len(model.Slices) {
+		return nil
+	}
+	if c := model.Cached; c != nil && c.Plane == model.Plane && len(c.Layers) == len(model.Slices) {
+		levels := make([]float64, len(model.Slices))
+		for i, layer := range model.Slices {
+			levels[i] = layer.Z
+		}
+		if sameLevels(c.Layers, levels) {
+			return nil
+		}
+	}
+	return &CachedSlices{model.Plane, model.Slices, model.Repairs}
+}
+
+// SliceProgress returns the fraction of layers sliced so far
+func (model *Model) SliceProgress() float64 {
+	if len(model.Slices) == 0 {
+		return 1
+	}
+	return float64(model.Sliced) / float64(len(model.Slices))
 }
 
 // Draw (MGD)
 func (model *Model) Draw() {
-	model.MeshVao.Draw()
+	for _, vao := range model.ChunkVaos {
+		vao.Draw()
+	}
 	// TODO draw active slice and bounding box
 }
 
+// DrawVisible draws the uploaded chunks in the view frustum of matrix, or
+// the preview until they are all uploaded
+func (model *Model) DrawVisible(matrix fauxgl.Matrix) {
+	if model.Preview != nil {
+		for _, vao := range model.Preview {
+			vao.Draw()
+		}
+		return
+	}
+	for i, vao := range model.ChunkVaos {
+		if BoxVisible(matrix, model.Chunks[i].Box) {
+			vao.Draw()
+		}
+	}
+}
+
+// UploadChunks uploads chunks until about budget triangles have gone to
+// the gpu, returning whether it uploaded any. An uploaded chunk keeps only
+// its box, for culling.
+func (model *Model) UploadChunks(budget int) bool {
+	uploaded := 0
+	for len(model.ChunkVaos) < len(model.Chunks) && uploaded < budget {
+		c := &model.Chunks[len(model.ChunkVaos)]
+		model.ChunkVaos = append(model.ChunkVaos, NewIndexedVao(c.Vertices, c.Indices))
+		uploaded += len(c.Indices) / 3
+		c.Vertices, c.Indices = nil, nil
+	}
+	if model.Indexed != nil && len(model.ChunkVaos) == len(model.Chunks) && model.Preview != nil {
+		model.DeletePreview()
+		return true
+	}
+	return uploaded > 0
+}
+
+// DeletePreview frees the preview
+func (model *Model) DeletePreview() {
+	for _, vao := range model.Preview {
+		vao.Delete()
+	}
+	model.Preview = nil
+}
+
+// UploadProgress returns the fraction of chunks uploaded so far
+func (model *Model) UploadProgress() float64 {
+	if len(model.Chunks) == 0 {
+		return 1
+	}
+	return float64(len(model.ChunkVaos)) / float64(len(model.Chunks))
+}
+
 // Destroy (MGD)
 func (model *Model) Destroy() {
-	gl.DeleteBuffers(1, &model.MeshVao.Buf)
+	for _, vao := range model.ChunkVaos {
+		vao.Delete()
+	}
+	if model.ShadeBuf != 0 {
+		gl.DeleteBuffers(1, &model.ShadeBuf)
+	}
+	for _, buf := range model.ShadeBufs {
+		gl.DeleteBuffers(1, &buf)
+	}
+	model.ShadeVao.Delete()
+	model.ShellVao.Delete()
+	model.ProxyVao.Delete()
+	model.DeleteSliceVaos()
+	model.DeletePreview()
+	for _, buf := range model.ProxyShadeBufs {
+		gl.DeleteBuffers(1, &buf)
+	}
 }
 
-// LoadModel loads a mesh and creates the model
+// DeleteSliceVaos frees the uploaded layers
+func (model *Model) DeleteSliceVaos() {
+	for _, vaos := range model.SliceVaos {
+		for _, vao := range vaos {
+			vao.Delete()
+		}
+	}
+	model.SliceVaos = nil
+}
+
+// StreamModel loads a mesh and creates the model, passing fn each batch of
+// triangles as StreamMesh reads them
+func StreamModel(ctx context.Context, path string, fn func(buffer []float32, progress float64)) (*Model, error) {
+	return streamModel(ctx, ModelCache, path, fn)
+}
+
+// streamModel is StreamModel reading cache
+func streamModel(ctx context.Context, cache *Cache, path string, fn func(buffer []float32, progress float64)) (*Model, error) {
+	key := cache.Key(path)
+	if model := cachedModel(cache, key, path); model != nil {
+		return model, nil
+	}
+	data, err := StreamMesh(ctx, path, fn)
+	if err != nil {
+		return nil, err
+	}
+	model := NewModel(data)
+	model.Path = path
+	model.CacheKey = key
+	return model, nil
+}
+
+// LoadModel loads a mesh and creates the model, from the cache if it holds
+// the file unchanged
 func LoadModel(path string) (*Model, error) {
-	mesh, err := fauxgl.LoadMesh(path)
+	key := ModelCache.Key(path)
+	if model := cachedModel(ModelCache, key, path); model != nil {
+		return model, nil
+	}
+	data, err := LoadMesh(path)
 	if err != nil {
 		return nil, err
 	}
 	log.Println("loaded model")
-	return NewModel(mesh), nil
+	model := NewModel(data)
+	model.Path = path
+	model.CacheKey = key
+	return model, nil
+}
+
+// cachedModel returns the model for path cached under key in cache, or
+// nil if it is not there
+func cachedModel(cache *Cache, key, path string) *Model {
+	model, err := cache.Load(key)
+	if err != nil {
+		if err != errCacheMiss {
+			log.Println("cache error", err)
+		}
+		return nil
+	}
+	log.Println("loaded", path, "from cache")
+	model.Path = path
+	return model
 }
 
 
@@ -190,15 +545,23 @@ func NewMesh(data *MeshData) *Mesh {
 
 // Slice generates a vbo for the slice at z
 func (mesh *Mesh) Slice(z float64) {
-	// copy triangles
+	mesh.SlicePlane(NewPlane(fauxgl.V(0, 0, z), PlaneZ.Normal))
+}
+
+// SlicePlane generates a vbo for the slice along plane
+func (mesh *Mesh) SlicePlane(plane Plane) {
+	// copy triangles into plane space
+	basis := plane.Basis()
 	triangles := make([]*slicer.Triangle, len(mesh.Triangles))
 	for i, t := range mesh.Triangles {
-		triangles[i] = slicer.NewTriangle(t)
+		triangles[i] = slicer.NewTriangle(planeTriangle(t, basis))
 	}
-	paths := slicer.GetPaths(triangles, z)
+	paths := slicer.GetPaths(triangles, plane.Offset())
+	inverse := plane.Inverse()
 	buffer := []float32{}
 	for _, path := range paths {
 		for _, v := range path {
+			v = inverse.MulPosition(v)
 			buffer = append(buffer, float32(v.X))
 			buffer = append(buffer, float32(v.Y))
 			buffer = append(buffer, float32(v.Z))
@@ -0,0 +1,341 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// RepairOptions chooses what Repair does to a mesh
+type RepairOptions struct {
+	Tolerance    float64 // vertex welding distance
+	FixNormals   bool    // orient faces consistently and outward
+	FillHoles    bool    // triangulate simple boundary loops
+	MaxHoleEdges int     // largest hole to fill; zero for any size
+}
+
+// DefaultRepairOptions returns options that do everything, welding at a
+// tolerance suited to the mesh
+func DefaultRepairOptions(data *MeshData) RepairOptions {
+	return RepairOptions{WeldTolerance(data.Box), true, true, 0}
+}
+
+// RepairReport counts what Repair changed
+type RepairReport struct {
+	Welded      int // vertices merged into others
+	Degenerate  int // zero area triangles removed
+	Duplicates  int // repeated triangles removed
+	Flipped     int // triangles reoriented
+	HolesFilled int
+	HolesLeft   int // boundary loops too large or complex to fill
+	Added       int // triangles added filling holes
+}
+
+// Print writes a readable summary of the report
+func (r RepairReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "welded        %d\n", r.Welded)
+	fmt.Fprintf(w, "degenerate    %d\n", r.Degenerate)
+	fmt.Fprintf(w, "duplicates    %d\n", r.Duplicates)
+	fmt.Fprintf(w, "flipped       %d\n", r.Flipped)
+	fmt.Fprintf(w, "holes filled  %d\n", r.HolesFilled)
+	fmt.Fprintf(w, "holes left    %d\n", r.HolesLeft)
+	fmt.Fprintf(w, "added         %d\n", r.Added)
+}
+
+// Repair returns a cleaned up copy of data: duplicate vertices are merged,
+// zero area and repeated triangles removed, faces oriented consistently
+// outward and simple holes filled, as chosen by opt
+func Repair(data *MeshData, opt RepairOptions) (*MeshData, RepairReport) {
+	var r RepairReport
+	vertices, indices := Weld(data.Buffer, opt.Tolerance)
+	r.Welded = len(indices) - len(vertices)
+
+	// drop degenerate and repeated triangles
+	var faces [][3]uint32
+	seen := make(map[[3]uint32]bool)
+	for i := 0; i+2 < len(indices); i += 3 {
+		f := [3]uint32{indices[i], indices[i+1], indices[i+2]}
+		a, b, c := vertices[f[0]], vertices[f[1]], vertices[f[2]]
+		if f[0] == f[1] || f[1] == f[2] || f[2] == f[0] || b.Sub(a).Cross(c.Sub(a)).Length() == 0 {
+			r.Degenerate++
+			continue
+		}
+		k := f
+		if k[0] > k[1] {
+			k[0], k[1] = k[1], k[0]
+		}
+		if k[1] > k[2] {
+			k[1], k[2] = k[2], k[1]
+		}
+		if k[0] > k[1] {
+			k[0], k[1] = k[1], k[0]
+		}
+		if seen[k] {
+			r.Duplicates++
+			continue
+		}
+		seen[k] = true
+		faces = append(faces, f)
+	}
+
+	// neighbors are made to agree before filling, so each hole's boundary
+	// runs one way, and shells the filling closed are oriented by their
+	// volume after
+	var original [][3]uint32
+	if opt.FixNormals {
+		original = append(original, faces...)
+		orientFaces(vertices, faces)
+	}
+	if opt.FillHoles {
+		var added [][3]uint32
+		added, r.HolesFilled, r.HolesLeft = fillHoles(vertices, faces, opt.MaxHoleEdges)
+		r.Added = len(added)
+		faces = append(faces, added...)
+	}
+	if opt.FixNormals {
+		orientFaces(vertices, faces)
+		for i, f := range original {
+			if faces[i] != f {
+				r.Flipped++
+			}
+		}
+	}
+
+	buffer := make([]float32, 0, len(faces)*9)
+	for _, f := range faces {
+		for _, i := range f {
+			buffer = append(buffer, vertices[i].Points()...)
+		}
+	}
+	if len(buffer) == 0 {
+		return &MeshData{buffer, fauxgl.Box{}, nil}, r
+	}
+	return &MeshData{buffer, boxForData(buffer), nil}, r
+}
+
+// faceEdges maps each undirected edge to the faces using it
+func faceEdges(faces [][3]uint32) map[[2]uint32][]int {
+	edges := make(map[[2]uint32][]int)
+	for i, f := range faces {
+		for j := 0; j < 3; j++ {
+			k := edgeKey(f[j], f[(j+1)%3])
+			edges[k] = append(edges[k], i)
+		}
+	}
+	return edges
+}
+
+// traverses reports whether face f runs from p directly to q
+func traverses(f [3]uint32, p, q uint32) bool {
+	for j := 0; j < 3; j++ {
+		if f[j] == p && f[(j+1)%3] == q {
+			return true
+		}
+	}
+	return false
+}
+
+// orientFaces flips faces so that neighbors across manifold edges agree,
+// then flips each closed patch that encloses negative volume. An open
+// patch has no inside to go by, so it keeps the winding most of its faces
+// had.
+func orientFaces(vertices []fauxgl.Vector, faces [][3]uint32) {
+	edges := faceEdges(faces)
+	flip := func(i int) {
+		faces[i][1], faces[i][2] = faces[i][2], faces[i][1]
+	}
+	flipped := make([]bool, len(faces))
+	visited := make([]bool, len(faces))
+	for start := range faces {
+		if visited[start] {
+			continue
+		}
+		// walk the patch, making each neighbor run opposite across the edge
+		visited[start] = true
+		patch := []int{start}
+		closed := true
+		for n := 0; n < len(patch); n++ {
+			f := faces[patch[n]]
+			for j := 0; j < 3; j++ {
+				p, q := f[j], f[(j+1)%3]
+				users := edges[edgeKey(p, q)]
+				if len(users) != 2 {
+					closed = false
+					continue
+				}
+				for _, g := range users {
+					if visited[g] {
+						continue
+					}
+					visited[g] = true
+					if traverses(faces[g], p, q) {
+						flip(g)
+						flipped[g] = !flipped[g]
+					}
+					patch = append(patch, g)
+				}
+			}
+		}
+
+		// a closed patch should enclose positive volume, while the volume
+		// of an open one depends on where the origin is
+		inside := false
+		if closed {
+			var volume float64
+			for _, i := range patch {
+				f := faces[i]
+				volume += vertices[f[0]].Dot(vertices[f[1]].Cross(vertices[f[2]]))
+			}
+			inside = volume < 0
+		} else {
+			n := 0
+			for _, i := range patch {
+				if flipped[i] {
+					n++
+				}
+			}
+			inside = 2*n > len(patch)
+		}
+		if inside {
+			for _, i := range patch {
+				flip(i)
+				flipped[i] = !flipped[i]
+			}
+		}
+	}
+}
+
+// fillHoles triangulates the simple boundary loops of faces no longer than
+// maxEdges, returning the new faces and the number of loops filled and left
+func fillHoles(vertices []fauxgl.Vector, faces [][3]uint32, maxEdges int) ([][3]uint32, int, int) {
+	// a boundary edge p->q of a face is filled by a triangle running q->p
+	next := make(map[uint32]uint32)
+	branching := make(map[uint32]bool)
+	for k, users := range faceEdges(faces) {
+		if len(users) != 1 {
+			continue
+		}
+		p, q := k[0], k[1]
+		if !traverses(faces[users[0]], p, q) {
+			p, q = q, p
+		}
+		if _, ok := next[q]; ok {
+			branching[q] = true
+		}
+		next[q] = p
+	}
+
+	var added [][3]uint32
+	filled, left := 0, 0
+	done := make(map[uint32]bool)
+	starts := make([]uint32, 0, len(next))
+	for v := range next {
+		starts = append(starts, v)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	for _, start := range starts {
+		if done[start] {
+			continue
+		}
+		var loop []uint32
+		simple := true
+		for v := start; ; {
+			if done[v] {
+				simple = false
+				break
+			}
+			done[v] = true
+			if branching[v] {
+				simple = false
+			}
+			loop = append(loop, v)
+			w, ok := next[v]
+			if !ok {
+				simple = false
+				break
+			}
+			if w == start {
+				break
+			}
+			v = w
+		}
+		if !simple || len(loop) < 3 || (maxEdges > 0 && len(loop) > maxEdges) {
+			left++
+			continue
+		}
+		added = append(added, triangulateLoop(vertices, loop)...)
+		filled++
+	}
+	return added, filled, left
+}
+
+// triangulateLoop ear clips a loop of vertices, projected onto its best fit
+// plane, into triangles wound the same way as the loop
+func triangulateLoop(vertices []fauxgl.Vector, loop []uint32) [][3]uint32 {
+	// newell normal of the loop
+	var n fauxgl.Vector
+	for i, a := range loop {
+		p := vertices[a]
+		q := vertices[loop[(i+1)%len(loop)]]
+		n = n.Add(fauxgl.V((p.Y-q.Y)*(p.Z+q.Z), (p.Z-q.Z)*(p.X+q.X), (p.X-q.X)*(p.Y+q.Y)))
+	}
+	fan := func() [][3]uint32 {
+		var result [][3]uint32
+		for i := 1; i+1 < len(loop); i++ {
+			result = append(result, [3]uint32{loop[0], loop[i], loop[i+1]})
+		}
+		return result
+	}
+	if n.Length() == 0 {
+		return fan()
+	}
+	basis := NewPlane(fauxgl.Vector{}, n).Basis()
+	points := make([]fauxgl.Vector, len(loop))
+	for i, a := range loop {
+		points[i] = basis.MulPosition(vertices[a])
+	}
+	cross := func(a, b, c fauxgl.Vector) float64 {
+		return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	}
+
+	var result [][3]uint32
+	remaining := make([]int, len(loop))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) > 3 {
+		found := false
+		m := len(remaining)
+		for i := 0; i < m; i++ {
+			ia, ib, ic := remaining[(i+m-1)%m], remaining[i], remaining[(i+1)%m]
+			a, b, c := points[ia], points[ib], points[ic]
+			if cross(a, b, c) <= 0 {
+				continue
+			}
+			ear := true
+			for _, j := range remaining {
+				if j == ia || j == ib || j == ic {
+					continue
+				}
+				p := points[j]
+				if cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+			result = append(result, [3]uint32{loop[ia], loop[ib], loop[ic]})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			found = true
+			break
+		}
+		if !found {
+			return fan()
+		}
+	}
+	return append(result, [3]uint32{loop[remaining[0]], loop[remaining[1]], loop[remaining[2]]})
+}
@@ -0,0 +1,86 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestRepair(t *testing.T) {
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))
+	// open the top, flip a side, repeat a face and add a sliver
+	triangles = append(triangles[:2], triangles[4:]...)
+	s := triangles[4]
+	triangles[4] = fauxgl.NewTriangleForPoints(s.V1.Position, s.V3.Position, s.V2.Position)
+	triangles = append(triangles, triangles[0])
+	triangles = append(triangles, fauxgl.NewTriangleForPoints(fauxgl.V(0, 0, 0), fauxgl.V(1, 0, 0), fauxgl.V(2, 0, 0)))
+
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	if Analyze(data, 1e-6).Watertight() {
+		t.Fatalf("broken box is watertight")
+	}
+	fixed, r := Repair(data, DefaultRepairOptions(data))
+	if r.Degenerate != 1 || r.Duplicates != 1 || r.Flipped != 1 || r.HolesFilled != 1 || r.Added != 2 {
+		t.Errorf("bad report %+v", r)
+	}
+	a := Analyze(fixed, 1e-6)
+	if !a.Watertight() {
+		t.Errorf("repaired box is not watertight: %+v", a)
+	}
+	if v := fixed.MassProperties(0).Volume; !nearly(v, 8) {
+		t.Errorf("volume %g, want 8", v)
+	}
+}
+
+func TestRepairOpenOrientation(t *testing.T) {
+	// an open box far from the origin encloses negative volume about it,
+	// however it is wound
+	triangles := boxTriangles(fauxgl.V(0, 0, 99), fauxgl.V(1, 1, 100))
+	triangles = append(triangles[:2], triangles[4:]...)
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	opt := DefaultRepairOptions(data)
+	opt.FillHoles = false
+	fixed, r := Repair(data, opt)
+	if r.Flipped != 0 {
+		t.Errorf("flipped %d faces of a well wound open box", r.Flipped)
+	}
+
+	// a stray face is turned to agree with the rest
+	s := triangles[4]
+	triangles[4] = fauxgl.NewTriangleForPoints(s.V1.Position, s.V3.Position, s.V2.Position)
+	data = FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	fixed, r = Repair(data, opt)
+	if r.Flipped != 1 {
+		t.Errorf("flipped %d faces, want 1", r.Flipped)
+	}
+	opt.FillHoles = true
+	fixed, _ = Repair(fixed, opt)
+	if v := fixed.MassProperties(0).Volume; !nearly(v, 1) {
+		t.Errorf("volume %g once closed, want 1", v)
+	}
+}
+
+func TestRepairInsideOut(t *testing.T) {
+	// an open box wound inside out is only known to be once its hole is
+	// filled, and a corner moved off by under the tolerance still welds
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))
+	triangles = append(triangles[:2], triangles[4:]...)
+	for i, s := range triangles {
+		triangles[i] = fauxgl.NewTriangleForPoints(s.V1.Position, s.V3.Position, s.V2.Position)
+	}
+	s := triangles[0]
+	triangles[0] = fauxgl.NewTriangleForPoints(s.V1.Position.Sub(fauxgl.V(6e-7, 0, 0)), s.V2.Position, s.V3.Position)
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+	opt := DefaultRepairOptions(data)
+	opt.Tolerance = 1e-6
+	fixed, r := Repair(data, opt)
+	if r.Flipped != len(triangles) || r.HolesFilled != 1 {
+		t.Errorf("bad report %+v", r)
+	}
+	if !Analyze(fixed, 1e-6).Watertight() {
+		t.Errorf("repaired box is not watertight")
+	}
+	if v := fixed.MassProperties(0).Volume; !nearlyRounded(v, 8) {
+		t.Errorf("volume %g, want 8", v)
+	}
+}
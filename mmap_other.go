@@ -0,0 +1,14 @@
+//go:build !unix
+
+package meshview
+
+import "os"
+
+// mapFile reads the file at path whole where it cannot be mapped
+func mapFile(path string) ([]byte, func() error, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, func() error { return nil }, nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package meshview
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the file at path into memory read only, returning its bytes
+// and a function that unmaps them. Reading past the end of a file cut
+// short while mapped faults, so it is only for files replaced by rename,
+// never rewritten in place.
+func mapFile(path string) ([]byte, func() error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	b, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, func() error { return syscall.Munmap(b) }, nil
+}
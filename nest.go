@@ -0,0 +1,119 @@
+package meshview
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fogleman/slicer"
+)
+
+// Contour is a closed slice path with the contours nested directly inside
+// it. Outer contours hold holes, which hold islands, and so on.
+type Contour struct {
+	Path     slicer.Path
+	Area     float64 // signed, positive when counterclockwise
+	Hole     bool
+	Children []*Contour
+}
+
+// LayerStats summarizes the contours of one layer
+type LayerStats struct {
+	Area      float64 // filled area, holes subtracted
+	Perimeter float64
+	Islands   int
+	Holes     int
+}
+
+// PathArea returns the signed area of path in X, Y, positive when
+// counterclockwise
+func PathArea(path slicer.Path) float64 {
+	var a float64
+	n := len(path)
+	for i := 0; i < n; i++ {
+		p := path[i]
+		q := path[(i+1)%n]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
+
+// PathLength returns the length of path, closing it if needed
+func PathLength(path slicer.Path) float64 {
+	var d float64
+	n := len(path)
+	for i := 0; i < n; i++ {
+		d += path[i].Distance(path[(i+1)%n])
+	}
+	return d
+}
+
+// pathContains reports whether x, y lies inside path
+func pathContains(path slicer.Path, x, y float64) bool {
+	inside := false
+	n := len(path)
+	for i := 0; i < n; i++ {
+		a := path[i]
+		b := path[(i+1)%n]
+		if (a.Y <= y) != (b.Y <= y) && x < a.X+(y-a.Y)/(b.Y-a.Y)*(b.X-a.X) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// NestPaths organizes closed paths into a forest of outer contours, each
+// holding its holes, by containment
+func NestPaths(paths []slicer.Path) []*Contour {
+	contours := make([]*Contour, 0, len(paths))
+	for _, path := range paths {
+		if len(path) < 3 {
+			continue
+		}
+		contours = append(contours, &Contour{Path: path, Area: PathArea(path)})
+	}
+	// a contour can only be inside a larger one
+	sort.SliceStable(contours, func(i, j int) bool {
+		return math.Abs(contours[i].Area) > math.Abs(contours[j].Area)
+	})
+
+	var roots []*Contour
+	for i, c := range contours {
+		// the smallest earlier contour containing c is its parent
+		v := c.Path[0]
+		var parent *Contour
+		for j := i - 1; j >= 0; j-- {
+			if pathContains(contours[j].Path, v.X, v.Y) {
+				parent = contours[j]
+				break
+			}
+		}
+		if parent == nil {
+			roots = append(roots, c)
+			continue
+		}
+		c.Hole = !parent.Hole
+		parent.Children = append(parent.Children, c)
+	}
+	return roots
+}
+
+// NewLayerStats computes the area and perimeter of nested contours
+func NewLayerStats(contours []*Contour) LayerStats {
+	var s LayerStats
+	var walk func([]*Contour)
+	walk = func(contours []*Contour) {
+		for _, c := range contours {
+			s.Perimeter += PathLength(c.Path)
+			if c.Hole {
+				s.Area -= math.Abs(c.Area)
+				s.Holes++
+			} else {
+				s.Area += math.Abs(c.Area)
+				s.Islands++
+			}
+			walk(c.Children)
+		}
+	}
+	walk(contours)
+	return s
+}
@@ -0,0 +1,47 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+func square(x0, y0, x1, y1 float64) slicer.Path {
+	return slicer.Path{
+		fauxgl.V(x0, y0, 0), fauxgl.V(x1, y0, 0), fauxgl.V(x1, y1, 0), fauxgl.V(x0, y1, 0), fauxgl.V(x0, y0, 0),
+	}
+}
+
+func TestNestPaths(t *testing.T) {
+	paths := []slicer.Path{
+		square(4, 4, 6, 6),   // island in the hole
+		square(0, 0, 10, 10), // outer
+		square(2, 2, 8, 8),   // hole
+		square(20, 0, 21, 1), // separate island
+	}
+	roots := NestPaths(paths)
+	if len(roots) != 2 {
+		t.Fatalf("got %d roots, want 2", len(roots))
+	}
+	outer := roots[0]
+	if outer.Hole || len(outer.Children) != 1 {
+		t.Fatalf("bad outer contour")
+	}
+	hole := outer.Children[0]
+	if !hole.Hole || len(hole.Children) != 1 || hole.Children[0].Hole {
+		t.Fatalf("bad hole")
+	}
+
+	s := NewLayerStats(roots)
+	if math.Abs(s.Area-(100-36+4+1)) > 1e-9 {
+		t.Errorf("area %g", s.Area)
+	}
+	if math.Abs(s.Perimeter-(40+24+8+4)) > 1e-9 {
+		t.Errorf("perimeter %g", s.Perimeter)
+	}
+	if s.Islands != 3 || s.Holes != 1 {
+		t.Errorf("%d islands %d holes", s.Islands, s.Holes)
+	}
+}
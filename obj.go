@@ -18,6 +18,16 @@ func parseIndex(value string, count int) int {
 
 // LoadOBJ (MGD)
 func LoadOBJ(path string) (*MeshData, error) {
+	indexed, err := LoadIndexedOBJ(path)
+	if err != nil {
+		return nil, err
+	}
+	return indexed.MeshData(), nil
+}
+
+// LoadIndexedOBJ loads an obj file keeping its vertices shared, fanning
+// polygons into triangles
+func LoadIndexedOBJ(path string) (*IndexedMeshData, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -25,8 +35,8 @@ func LoadOBJ(path string) (*MeshData, error) {
 	defer file.Close()
 
 	count := 1
-	lookup := make([]float32, 3, 1024)
-	var data []float32
+	var vertices []float32
+	var indices []uint32
 	var indexes []int
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -42,8 +52,7 @@ func LoadOBJ(path string) (*MeshData, error) {
 			x, _ := strconv.ParseFloat(args[0], 32)
 			y, _ := strconv.ParseFloat(args[1], 32)
 			z, _ := strconv.ParseFloat(args[2], 32)
-			v := []float32{float32(x), float32(y), float32(z)}
-			lookup = append(lookup, v...)
+			vertices = append(vertices, float32(x), float32(y), float32(z))
 			count++
 		case "f":
 			indexes = indexes[:0]
@@ -55,21 +64,14 @@ func LoadOBJ(path string) (*MeshData, error) {
 				index := parseIndex(arg, count)
 				indexes = append(indexes, index)
 			}
+			// obj indices count from 1
 			for i := 1; i < len(indexes)-1; i++ {
-				i1 := indexes[0] * 3
-				i2 := indexes[i] * 3
-				i3 := indexes[i+1] * 3
-				data = append(data, lookup[i1:i1+3]...)
-				data = append(data, lookup[i2:i2+3]...)
-				data = append(data, lookup[i3:i3+3]...)
+				indices = append(indices, uint32(indexes[0]-1), uint32(indexes[i]-1), uint32(indexes[i+1]-1))
 			}
 		}
 	}
-
-	box := boxForData(data)
-	//md := MeshData{}
-	//md.Buffer = data
-	//md.Box = box
-	//return &md, scanner.Err()
-	return &MeshData{data, box, nil}, scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewIndexedMeshData(vertices, indices), nil
 }
@@ -0,0 +1,90 @@
+package meshview
+
+import (
+	"math"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Overhang classifies a face by how far it leans out over the build plate
+type Overhang int
+
+// Faces are fine up to the warning angle, steep up to the critical angle,
+// and need support beyond it
+const (
+	OverhangNone Overhang = iota
+	OverhangSteep
+	OverhangSupport
+)
+
+// OverhangOptions sets the build direction and the overhang angles, in
+// degrees from vertical, at which faces turn steep and need support
+type OverhangOptions struct {
+	Up            fauxgl.Vector
+	WarnAngle     float64
+	CriticalAngle float64
+}
+
+// DefaultOverhangOptions builds along +Z, warning past 45 degrees and
+// needing support past 60
+var DefaultOverhangOptions = OverhangOptions{fauxgl.V(0, 0, 1), 45, 60}
+
+// OverhangColors are the heatmap colors of each Overhang class
+var OverhangColors = [...]fauxgl.Vector{
+	OverhangNone:    fauxgl.V(0.2, 0.8, 0.2),
+	OverhangSteep:   fauxgl.V(0.95, 0.85, 0.1),
+	OverhangSupport: fauxgl.V(0.9, 0.1, 0.1),
+}
+
+// FindOverhangs classifies each triangle of data by its overhang angle and
+// returns the classes and the total area needing support. Down facing
+// triangles resting on the build plate need none.
+func FindOverhangs(data *MeshData, opt OverhangOptions) ([]Overhang, float64) {
+	up := opt.Up.Normalize()
+	triangles := BufferTriangles(data.Buffer)
+
+	// the lowest point along up is the build plate
+	floor := math.Inf(1)
+	for _, t := range triangles {
+		for _, v := range t {
+			floor = math.Min(floor, v.Dot(up))
+		}
+	}
+	tolerance := WeldTolerance(data.Box)
+
+	warn := math.Cos(fauxgl.Radians(90 - opt.WarnAngle))
+	critical := math.Cos(fauxgl.Radians(90 - opt.CriticalAngle))
+	classes := make([]Overhang, len(triangles))
+	var area float64
+	for i, t := range triangles {
+		n := t[1].Sub(t[0]).Cross(t[2].Sub(t[0]))
+		a := n.Length() / 2
+		if a == 0 {
+			continue
+		}
+		// how directly the face points down, 0 for a wall, 1 for a ceiling
+		down := -n.Dot(up) / (2 * a)
+		onPlate := true
+		for _, v := range t {
+			if v.Dot(up)-floor > tolerance {
+				onPlate = false
+			}
+		}
+		switch {
+		case onPlate || down <= warn:
+		case down <= critical:
+			classes[i] = OverhangSteep
+		default:
+			classes[i] = OverhangSupport
+			area += a
+		}
+	}
+	return classes, area
+}
+
+// OverhangFaceColors returns the heatmap color buffer for classes
+func OverhangFaceColors(classes []Overhang) []float32 {
+	return FaceColors(len(classes), func(i int) fauxgl.Vector {
+		return OverhangColors[classes[i]]
+	})
+}
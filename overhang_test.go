@@ -0,0 +1,45 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestFindOverhangs(t *testing.T) {
+	// a box on the plate, a box floating above it and a face leaning 50
+	// degrees out past vertical
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1))
+	triangles = append(triangles, boxTriangles(fauxgl.V(0, 0, 2), fauxgl.V(2, 1, 3))...)
+	a := fauxgl.Radians(50)
+	p := fauxgl.V(0, 0, 5)
+	triangles = append(triangles, fauxgl.NewTriangleForPoints(p, p.Add(fauxgl.V(0, 1, 0)), p.Add(fauxgl.V(math.Sin(a), 0, math.Cos(a)))))
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+
+	classes, area := FindOverhangs(data, DefaultOverhangOptions)
+	if len(classes) != 25 {
+		t.Fatalf("%d classes, want 25", len(classes))
+	}
+	if !nearly(area, 2) {
+		t.Errorf("support area %g, want 2", area)
+	}
+	counts := make(map[Overhang]int)
+	for _, c := range classes {
+		counts[c]++
+	}
+	if counts[OverhangSupport] != 2 || counts[OverhangSteep] != 1 {
+		t.Errorf("%d support and %d steep faces, want 2 and 1", counts[OverhangSupport], counts[OverhangSteep])
+	}
+	for i := 0; i < 12; i++ {
+		if classes[i] != OverhangNone {
+			t.Errorf("face %d of the box on the plate is %d", i, classes[i])
+		}
+	}
+
+	// with a steeper critical angle the leaning face needs support too
+	_, area = FindOverhangs(data, OverhangOptions{fauxgl.V(0, 0, 1), 30, 45})
+	if math.Abs(area-2.5) > 1e-6 { // float32 corners
+		t.Errorf("support area %g, want 2.5", area)
+	}
+}
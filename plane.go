@@ -0,0 +1,111 @@
+package meshview
+
+import (
+	"math"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Plane is a slicing plane through Point with unit Normal. Layers are cut
+// parallel to it, one of them passing through Point.
+type Plane struct {
+	Point  fauxgl.Vector
+	Normal fauxgl.Vector
+}
+
+// NewPlane makes a Plane, normalizing normal
+func NewPlane(point, normal fauxgl.Vector) Plane {
+	return Plane{point, normal.Normalize()}
+}
+
+// PlaneX, PlaneY and PlaneZ are the axis planes through the origin
+var (
+	PlaneX = Plane{fauxgl.Vector{}, fauxgl.V(1, 0, 0)}
+	PlaneY = Plane{fauxgl.Vector{}, fauxgl.V(0, 1, 0)}
+	PlaneZ = Plane{fauxgl.Vector{}, fauxgl.V(0, 0, 1)}
+)
+
+// Basis returns the rotation from world space into plane space, where the
+// normal is +Z. For PlaneZ it is the identity, so Z slices keep their
+// world coordinates.
+func (p Plane) Basis() fauxgl.Matrix {
+	n := p.Normal
+	u := fauxgl.V(0, 1, 0).Cross(n)
+	if u.Length() < 1e-9 {
+		u = n.Cross(fauxgl.V(0, 0, 1))
+	}
+	u = u.Normalize()
+	v := n.Cross(u)
+	return fauxgl.Matrix{
+		X00: u.X, X01: u.Y, X02: u.Z,
+		X10: v.X, X11: v.Y, X12: v.Z,
+		X20: n.X, X21: n.Y, X22: n.Z,
+		X33: 1,
+	}
+}
+
+// Inverse returns the rotation from plane space back into world space
+func (p Plane) Inverse() fauxgl.Matrix {
+	return p.Basis().Transpose()
+}
+
+// Offset returns the plane space Z of the plane itself
+func (p Plane) Offset() float64 {
+	return p.Normal.Dot(p.Point)
+}
+
+// Extent returns the range of plane space Z covered by the vertices of a
+// de-indexed triangle buffer
+func (p Plane) Extent(buffer []float32) (lo, hi float64) {
+	lo = math.Inf(1)
+	hi = math.Inf(-1)
+	for i := 0; i+2 < len(buffer); i += 3 {
+		v := fauxgl.V(float64(buffer[i]), float64(buffer[i+1]), float64(buffer[i+2]))
+		d := p.Normal.Dot(v)
+		lo = math.Min(lo, d)
+		hi = math.Max(hi, d)
+	}
+	return
+}
+
+// Levels returns the plane space Z values of the layers strictly inside
+// the triangles of buffer, spaced so that about count of them span the mesh
+func (p Plane) Levels(buffer []float32, count int) []float64 {
+	if len(buffer) < 9 || count < 1 {
+		return nil
+	}
+	lo, hi := p.Extent(buffer)
+	return p.levels(lo, hi, (hi-lo)/float64(count))
+}
+
+// LevelsStep returns the plane space Z values of the layers strictly inside
+// the triangles of buffer, step apart
+func (p Plane) LevelsStep(buffer []float32, step float64) []float64 {
+	if len(buffer) < 9 || step <= 0 {
+		return nil
+	}
+	lo, hi := p.Extent(buffer)
+	return p.levels(lo, hi, step)
+}
+
+func (p Plane) levels(lo, hi, step float64) []float64 {
+	if step == 0 {
+		return []float64{lo}
+	}
+	offset := p.Offset()
+	k0 := math.Floor((lo-offset)/step) + 1
+	k1 := math.Ceil((hi-offset)/step) - 1
+	var levels []float64
+	for k := k0; k <= k1; k++ {
+		levels = append(levels, offset+k*step)
+	}
+	return levels
+}
+
+// planeTriangle returns a copy of t rotated into plane space by basis
+func planeTriangle(t *fauxgl.Triangle, basis fauxgl.Matrix) *fauxgl.Triangle {
+	return fauxgl.NewTriangleForPoints(
+		basis.MulPosition(t.V1.Position),
+		basis.MulPosition(t.V2.Position),
+		basis.MulPosition(t.V3.Position))
+}
@@ -0,0 +1,47 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestPlaneBasis(t *testing.T) {
+	if PlaneZ.Basis() != fauxgl.Identity() {
+		t.Errorf("z basis is not identity")
+	}
+	for _, n := range []fauxgl.Vector{fauxgl.V(1, 0, 0), fauxgl.V(0, 1, 0), fauxgl.V(1, 2, 3)} {
+		p := NewPlane(fauxgl.V(1, 1, 1), n)
+		z := p.Basis().MulPosition(p.Normal)
+		if z.Sub(fauxgl.V(0, 0, 1)).Length() > 1e-9 {
+			t.Errorf("normal %v maps to %v", n, z)
+		}
+		v := fauxgl.V(4, 5, 6)
+		w := p.Inverse().MulPosition(p.Basis().MulPosition(v))
+		if w.Sub(v).Length() > 1e-9 {
+			t.Errorf("inverse of %v maps %v to %v", n, v, w)
+		}
+	}
+}
+
+func TestPlaneLevels(t *testing.T) {
+	buffer := []float32{0, 0, 0, 1, 0, 0, 0, 0, 10}
+	p := NewPlane(fauxgl.V(0, 0, 5), fauxgl.V(0, 0, 1))
+	levels := p.Levels(buffer, 10)
+	if len(levels) != 9 {
+		t.Fatalf("got %d levels, want 9", len(levels))
+	}
+	found := false
+	for _, z := range levels {
+		if z <= 0 || z >= 10 {
+			t.Errorf("level %g outside mesh", z)
+		}
+		if math.Abs(z-5) < 1e-9 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no level through plane point")
+	}
+}
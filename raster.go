@@ -0,0 +1,149 @@
+package meshview
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+// RasterOptions describes the bitmap a layer is rasterized into
+type RasterOptions struct {
+	Width     int           // pixels
+	Height    int           // pixels
+	PixelSize float64       // millimeters per pixel
+	Center    fauxgl.Vector // layer X, Y at the center of the image
+	NonZero   bool          // fill by nonzero winding instead of even-odd
+	AntiAlias int           // samples per pixel along each axis; 0 or 1 is off
+}
+
+// crossing is where an edge crosses a scanline, with its winding direction
+type crossing struct {
+	X       float64
+	Winding int
+}
+
+// RasterizeLayer fills the closed paths of layer into a grayscale image,
+// white where exposed
+func RasterizeLayer(layer slicer.Layer, opt RasterOptions) *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, opt.Width, opt.Height))
+	s := opt.AntiAlias
+	if s < 1 {
+		s = 1
+	}
+	// x0, y1 are the layer coordinates of the image's top left corner
+	x0 := opt.Center.X - float64(opt.Width)/2*opt.PixelSize
+	y1 := opt.Center.Y + float64(opt.Height)/2*opt.PixelSize
+	step := opt.PixelSize / float64(s)
+
+	coverage := make([]int, opt.Width)
+	var crossings []crossing
+	for row := 0; row < opt.Height; row++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+		for k := 0; k < s; k++ {
+			y := y1 - (float64(row*s+k)+0.5)*step
+			crossings = crossings[:0]
+			for _, path := range layer.Paths {
+				n := len(path)
+				for i := 0; i < n; i++ {
+					a := path[i]
+					b := path[(i+1)%n]
+					if (a.Y <= y) == (b.Y <= y) {
+						continue
+					}
+					x := a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+					w := 1
+					if b.Y < a.Y {
+						w = -1
+					}
+					crossings = append(crossings, crossing{x, w})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool {
+				return crossings[i].X < crossings[j].X
+			})
+			winding := 0
+			for i := 0; i+1 < len(crossings); i++ {
+				winding += crossings[i].Winding
+				inside := winding%2 != 0
+				if opt.NonZero {
+					inside = winding != 0
+				}
+				if !inside {
+					continue
+				}
+				// cover the subsamples whose centers fall in the span
+				j0 := int(math.Ceil((crossings[i].X-x0)/step - 0.5))
+				j1 := int(math.Ceil((crossings[i+1].X-x0)/step - 0.5))
+				if j0 < 0 {
+					j0 = 0
+				}
+				if j1 > opt.Width*s {
+					j1 = opt.Width * s
+				}
+				for j := j0; j < j1; j++ {
+					coverage[j/s]++
+				}
+			}
+		}
+		line := im.Pix[row*im.Stride:]
+		for i, c := range coverage {
+			line[i] = uint8(c * 255 / (s * s))
+		}
+	}
+	return im
+}
+
+// SavePNGStack rasterizes layers into numbered pngs, written into a zip if
+// path ends in .zip or else into the directory path
+func SavePNGStack(path string, layers []slicer.Layer, opt RasterOptions) error {
+	if strings.ToLower(filepath.Ext(path)) == ".zip" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		archive := zip.NewWriter(file)
+		for i, layer := range layers {
+			w, err := archive.Create(fmt.Sprintf("%04d.png", i))
+			if err != nil {
+				return err
+			}
+			if err := encodeLayer(w, layer, opt); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	for i, layer := range layers {
+		file, err := os.Create(filepath.Join(path, fmt.Sprintf("%04d.png", i)))
+		if err != nil {
+			return err
+		}
+		err = encodeLayer(file, layer, opt)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeLayer(w io.Writer, layer slicer.Layer, opt RasterOptions) error {
+	return png.Encode(w, RasterizeLayer(layer, opt))
+}
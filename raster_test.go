@@ -0,0 +1,37 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+func TestRasterizeLayer(t *testing.T) {
+	// a 10mm square with a 4mm square hole wound the same way
+	layer := slicer.Layer{Paths: []slicer.Path{square(0, 0, 10, 10), square(3, 3, 7, 7)}}
+	opt := RasterOptions{Width: 20, Height: 20, PixelSize: 1, Center: fauxgl.V(5, 5, 0)}
+	count := func() int {
+		n := 0
+		for _, p := range RasterizeLayer(layer, opt).Pix {
+			if p == 255 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(); n != 84 {
+		t.Errorf("even-odd filled %d pixels, want 84", n)
+	}
+	opt.NonZero = true
+	if n := count(); n != 100 {
+		t.Errorf("nonzero filled %d pixels, want 100", n)
+	}
+	opt.NonZero = false
+	opt.AntiAlias = 4
+	opt.Center = fauxgl.V(5.5, 5, 0)
+	im := RasterizeLayer(layer, opt)
+	if p := im.GrayAt(4, 10).Y; p != 127 {
+		t.Errorf("edge pixel is %d, want half covered", p)
+	}
+}
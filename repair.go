@@ -0,0 +1,175 @@
+package meshview
+
+import (
+	"math"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+// Gap is an unrepaired break in a slice path between its two loose ends
+type Gap struct {
+	Start fauxgl.Vector
+	End   fauxgl.Vector
+}
+
+// LayerRepair records what RepairPaths did to one layer
+type LayerRepair struct {
+	Snapped int // points moved onto the layer plane
+	Dropped int // degenerate segments and paths removed
+	Joined  int // open paths joined end to end or closed
+	Gaps    []Gap
+}
+
+// Defective reports whether the layer still has open paths
+func (r LayerRepair) Defective() bool {
+	return len(r.Gaps) > 0
+}
+
+// RepairPaths cleans up the paths of a layer at z: it snaps stray points
+// onto the plane, drops segments shorter than tolerance and paths with no
+// area, and joins open paths whose endpoints are within tolerance. Paths
+// that remain open are kept and reported as gaps.
+func RepairPaths(paths []slicer.Path, z, tolerance float64) ([]slicer.Path, LayerRepair) {
+	var r LayerRepair
+	near := func(a, b fauxgl.Vector) bool {
+		return a.Distance(b) <= tolerance
+	}
+
+	// snap and drop degenerate segments
+	var open, closed []slicer.Path
+	for _, path := range paths {
+		clean := make(slicer.Path, 0, len(path))
+		for _, v := range path {
+			if v.Z != z {
+				v.Z = z
+				r.Snapped++
+			}
+			if len(clean) > 0 && near(clean[len(clean)-1], v) {
+				r.Dropped++
+				continue
+			}
+			clean = append(clean, v)
+		}
+		if len(clean) < 2 {
+			r.Dropped++
+			continue
+		}
+		if near(clean[0], clean[len(clean)-1]) {
+			clean[len(clean)-1] = clean[0]
+			closed = append(closed, clean)
+		} else {
+			open = append(open, clean)
+		}
+	}
+
+	// join open paths at either end, reversing where needed, until no two
+	// loose ends are within tolerance; ends are found through a grid of
+	// cells tolerance wide, looking in the cells around each
+	size := tolerance
+	if size <= 0 {
+		size = 1
+	}
+	cell := func(v fauxgl.Vector) [2]int64 {
+		return [2]int64{int64(math.Floor(v.X / size)), int64(math.Floor(v.Y / size))}
+	}
+	type pathEnd struct {
+		Path int
+		Last bool
+	}
+	ends := make(map[[2]int64][]pathEnd)
+	for i, p := range open {
+		ends[cell(p[0])] = append(ends[cell(p[0])], pathEnd{i, false})
+		ends[cell(p[len(p)-1])] = append(ends[cell(p[len(p)-1])], pathEnd{i, true})
+	}
+	used := make([]bool, len(open))
+	// find returns the unused path with an end nearest v, the lowest
+	// numbered if several are, so the result does not depend on map order
+	find := func(v fauxgl.Vector) (pathEnd, bool) {
+		best, found := pathEnd{}, false
+		c := cell(v)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for _, e := range ends[[2]int64{c[0] + dx, c[1] + dy}] {
+					q := open[e.Path]
+					w := q[0]
+					if e.Last {
+						w = q[len(q)-1]
+					}
+					if used[e.Path] || !near(v, w) {
+						continue
+					}
+					if !found || e.Path < best.Path || e.Path == best.Path && !e.Last {
+						best, found = e, true
+					}
+				}
+			}
+		}
+		return best, found
+	}
+
+	var unjoined []slicer.Path
+	for i, p := range open {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		closes := func() bool {
+			return len(p) > 2 && near(p[0], p[len(p)-1])
+		}
+		// grow the end, then the start
+		for !closes() {
+			e, ok := find(p[len(p)-1])
+			if !ok {
+				break
+			}
+			used[e.Path] = true
+			q := open[e.Path]
+			if e.Last {
+				q = reversePath(q)
+			}
+			p = append(p, q[1:]...)
+			r.Joined++
+		}
+		for !closes() {
+			e, ok := find(p[0])
+			if !ok {
+				break
+			}
+			used[e.Path] = true
+			q := open[e.Path]
+			if !e.Last {
+				q = reversePath(q)
+			}
+			p = append(append(slicer.Path{}, q[:len(q)-1]...), p...)
+			r.Joined++
+		}
+		if closes() {
+			p[len(p)-1] = p[0]
+			closed = append(closed, p)
+			r.Joined++
+			continue
+		}
+		r.Gaps = append(r.Gaps, Gap{p[len(p)-1], p[0]})
+		unjoined = append(unjoined, p)
+	}
+
+	// drop closed paths that enclose nothing
+	result := closed[:0]
+	for _, path := range closed {
+		if len(path) < 4 {
+			r.Dropped++
+			continue
+		}
+		result = append(result, path)
+	}
+	return append(result, unjoined...), r
+}
+
+func reversePath(path slicer.Path) slicer.Path {
+	r := make(slicer.Path, len(path))
+	for i, v := range path {
+		r[len(path)-1-i] = v
+	}
+	return r
+}
@@ -0,0 +1,77 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+func TestRepairPaths(t *testing.T) {
+	const tol = 0.01
+	paths := []slicer.Path{
+		// a square split in two, the second half reversed and a hair apart
+		{fauxgl.V(0, 0, 1), fauxgl.V(1, 0, 1), fauxgl.V(1, 1, 1)},
+		{fauxgl.V(0, 0, 1.001), fauxgl.V(0, 1, 1), fauxgl.V(1, 1.005, 1)},
+		// a closed triangle with a duplicated point
+		{fauxgl.V(5, 5, 1), fauxgl.V(6, 5, 1), fauxgl.V(6, 5, 1), fauxgl.V(6, 6, 1), fauxgl.V(5, 5, 1)},
+		// an open path with nothing to join
+		{fauxgl.V(10, 10, 1), fauxgl.V(11, 10, 1), fauxgl.V(11, 11, 1)},
+	}
+	result, r := RepairPaths(paths, 1, tol)
+	if r.Snapped != 1 {
+		t.Errorf("snapped %d, want 1", r.Snapped)
+	}
+	if r.Dropped != 1 {
+		t.Errorf("dropped %d, want 1", r.Dropped)
+	}
+	if r.Joined != 2 {
+		t.Errorf("joined %d, want 2", r.Joined)
+	}
+	if len(r.Gaps) != 1 || r.Gaps[0].Start != fauxgl.V(11, 11, 1) {
+		t.Errorf("bad gaps %v", r.Gaps)
+	}
+	if len(result) != 3 {
+		t.Fatalf("got %d paths, want 3", len(result))
+	}
+	square := result[1]
+	if len(square) != 5 || square[0] != square[4] {
+		t.Errorf("square not closed: %v", square)
+	}
+}
+
+func TestRepairPathsOrder(t *testing.T) {
+	const tol = 0.01
+	paths := []slicer.Path{
+		// three sides of a square that only join through the start of one
+		{fauxgl.V(10, 0, 1), fauxgl.V(10, 10, 1), fauxgl.V(0, 10, 1)},
+		{fauxgl.V(0, 10, 1), fauxgl.V(0, 0, 1), fauxgl.V(5, -1, 1)},
+		{fauxgl.V(5, -1, 1), fauxgl.V(10, 0.005, 1)},
+		// an open path with nothing to join
+		{fauxgl.V(20, 20, 1), fauxgl.V(21, 20, 1), fauxgl.V(21, 21, 1)},
+	}
+	orders := [][]int{
+		{0, 1, 2, 3}, {1, 0, 2, 3}, {2, 1, 0, 3}, {3, 2, 1, 0}, {1, 2, 0, 3}, {2, 0, 3, 1},
+	}
+	for _, order := range orders {
+		permuted := make([]slicer.Path, len(order))
+		for i, j := range order {
+			permuted[i] = paths[j]
+		}
+		result, r := RepairPaths(permuted, 1, tol)
+		if len(result) != 2 || len(r.Gaps) != 1 || r.Joined != 3 {
+			t.Errorf("order %v: got %d paths, %d gaps, %d joined, want 2, 1, 3",
+				order, len(result), len(r.Gaps), r.Joined)
+		}
+	}
+
+	// the two halves of an open path join whichever comes first
+	p1 := slicer.Path{fauxgl.V(10, 0, 1), fauxgl.V(10, 10, 1), fauxgl.V(0, 10, 1)}
+	p2 := slicer.Path{fauxgl.V(0, 10, 1), fauxgl.V(0, 0, 1), fauxgl.V(5, -1, 1)}
+	for _, pair := range [][]slicer.Path{{p1, p2}, {p2, p1}} {
+		result, r := RepairPaths(pair, 1, tol)
+		if len(result) != 1 || len(r.Gaps) != 1 {
+			t.Errorf("got %d paths and %d gaps, want 1 and 1", len(result), len(r.Gaps))
+		}
+	}
+}
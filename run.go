@@ -1,25 +1,32 @@
 package meshview
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
 	"github.com/go-gl/gl/v2.1/gl"
 	//"github.com/go-gl/gl/v3.3-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
 )
 
 var vertexShader = `
 #version 120
 uniform mat4 matrix;
 attribute vec4 position;
+attribute vec3 color;
 varying vec3 ec_pos;
+varying vec3 object_color;
 void main() {
 	gl_Position = matrix * position;
 	ec_pos = vec3(gl_Position);
+	object_color = color;
 }
 `
 
@@ -27,7 +34,7 @@ var fragmentShader = `
 #version 120
 varying vec3 ec_pos;
 const vec3 light_direction = normalize(vec3(1, -1.5, 1));
-const vec3 object_color = vec3(0x5b / 255.0, 0xac / 255.0, 0xe3 / 255.0);
+varying vec3 object_color;
 void main() {
 	vec3 ec_normal = normalize(cross(dFdx(ec_pos), dFdy(ec_pos)));
 	float diffuse = max(0, dot(ec_normal, light_direction)) * 0.9 + 0.15;
@@ -40,207 +47,826 @@ func init() {
 	runtime.LockOSThread()
 }
 
-func loadModel(path string, ch chan *Model) {
+// loadBatch is a run of triangles read from a file still loading, or the
+// error its load stopped with
+type loadBatch struct {
+	Path     string
+	Buffer   []float32
+	Progress float64
+	Err      error
+}
+
+// preview holds the triangles of a file uploaded as they load, drawn until
+// its model arrives
+type preview struct {
+	Path     string
+	Vaos     []Vao
+	Box      fauxgl.Box
+	Progress float64
+}
+
+// Add uploads a batch of triangles
+func (p *preview) Add(b loadBatch) {
+	p.Progress = b.Progress
+	if len(b.Buffer) == 0 {
+		return
+	}
+	box := boxForData(b.Buffer)
+	if len(p.Vaos) > 0 {
+		box = box.Extend(p.Box)
+	}
+	p.Box = box
+	p.Vaos = append(p.Vaos, NewVao(b.Buffer))
+}
+
+// Destroy frees the uploaded batches
+func (p *preview) Destroy() {
+	for _, vao := range p.Vaos {
+		vao.Delete()
+	}
+}
+
+// loadModel loads the model at path in the background, sending batches of
+// it to preview as they are read and then the model, compared if the
+// viewer compares, and then its analyses
+func (v *Viewer) loadModel(ctx context.Context, path string) {
+	ch, batchCh, analysisCh := v.ch, v.batchCh, v.analysisCh
+	compare, alignment, scale := v.opts.Compare, v.opts.Alignment, v.opts.DeviationScale
+	overhang, cache := v.opts.Overhang, v.opts.Cache
+	// fail tells the viewer to stop waiting for the model
+	fail := func(err error) {
+		select {
+		case batchCh <- loadBatch{Path: path, Err: err}:
+			wake()
+		case <-ctx.Done():
+		}
+	}
 	go func() {
 		start := time.Now()
-		model, err := LoadModel(path)
+		model, err := streamModel(ctx, cache, path, func(buffer []float32, progress float64) {
+			select {
+			case batchCh <- loadBatch{path, buffer, progress, nil}:
+				wake()
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			log.Println("load of", path, "cancelled")
+			return
+		}
 		if err != nil {
-			log.Println("load error")
+			log.Println("load error", err)
+			fail(err)
 			return // TODO: display an error
 		}
-		log.Printf("loaded %d triangles in %.3f seconds\n", len(model.Mesh.Triangles), time.Since(start).Seconds())
-		ch <- model
+		log.Printf("loaded %d triangles in %.3f seconds\n", model.Data.Count(), time.Since(start).Seconds())
+		if compare != "" {
+			if model, err = compareModel(model, compare, alignment); err != nil {
+				log.Println("compare error", err)
+				fail(err)
+				return
+			}
+			model.DeviationLimit = scale
+		}
+		select {
+		case ch <- model:
+			wake()
+		case <-ctx.Done():
+			log.Println("load of", path, "cancelled")
+			return
+		}
+		findAnalysis(ctx, model, overhang, analysisCh)
 	}()
 }
 
-var sliceIndex = 0
-var sliceMax = 0
-var lastMatrix = fauxgl.Matrix{}
+// saveCache writes data and its layers to cache in the background
+func saveCache(cache *Cache, key string, data *MeshData, slices *CachedSlices) {
+	go func() {
+		if err := cache.Save(key, data, slices); err != nil {
+			log.Println("cache error", err)
+		}
+	}()
+}
 
-// Run (MGD)
-func Run(path string) {
+// compareModel aligns model with the mesh at path, per alignment, and
+// measures its deviation from it, returning it aligned
+func compareModel(model *Model, path string, alignment Alignment) (*Model, error) {
+	other, err := LoadMesh(path)
+	if err != nil {
+		return nil, err
+	}
 	start := time.Now()
+	aligned := NewModel(TransformData(model.Data, Align(model.Data, other, alignment)))
+	aligned.Path = model.Path
+	aligned.ComparePath = path
+	aligned.Deviation, aligned.Compare = Deviation(aligned.Data, other)
+	log.Printf("compared with %s in %.3f seconds\n", path, time.Since(start).Seconds())
+	aligned.Compare.Print(os.Stdout)
+	return aligned, nil
+}
 
-	// load model in the background
-	ch := make(chan *Model)
-	loadModel(path, ch)
+// analysisResult carries the indexed mesh and analyses made for a model
+type analysisResult struct {
+	Model        *Model
+	Indexed      *IndexedMeshData
+	Chunks       []Chunk
+	Analysis     *Analysis
+	Mass         MassProperties
+	Overhangs    []Overhang
+	OverhangArea float64
+	Shells       []Shell
+}
 
-	// initialize glfw
-	if err := glfw.Init(); err != nil {
-		panic(err)
+// analyzeModel indexes model's mesh and analyzes it, with overhangs per
+// overhang, stopping with the context's error if ctx is cancelled
+func analyzeModel(ctx context.Context, model *Model, overhang OverhangOptions) (analysisResult, error) {
+	data := model.Data
+	r := analysisResult{Model: model}
+	r.Indexed = data.Indexed()
+	r.Chunks = SplitChunks(r.Indexed, ChunkSize)
+	if err := ctx.Err(); err != nil {
+		return r, err
+	}
+	r.Analysis = Analyze(data, WeldTolerance(data.Box))
+	r.Mass = data.MassProperties(0)
+	if err := ctx.Err(); err != nil {
+		return r, err
 	}
-	defer glfw.Terminate()
+	r.Overhangs, r.OverhangArea = FindOverhangs(data, overhang)
+	r.Shells = FindShells(data)
+	if !r.Analysis.Watertight() {
+		log.Printf("%d boundary, %d non-manifold and %d badly wound edges\n",
+			len(r.Analysis.Boundary), len(r.Analysis.NonManifold), len(r.Analysis.Winding))
+	}
+	return r, ctx.Err()
+}
 
-	// create the window
-	glfw.WindowHint(glfw.Samples, 4)
-	glfw.WindowHint(glfw.ContextVersionMajor, 2)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	window, err := glfw.CreateWindow(1920, 1080, path, nil, nil)
-	if err != nil {
-		panic(err)
+// apply fills the indexed mesh and analyses into the model
+func (r analysisResult) apply() {
+	model := r.Model
+	model.Indexed, model.Chunks = r.Indexed, r.Chunks
+	model.Analysis, model.Mass = r.Analysis, r.Mass
+	model.Overhangs, model.OverhangArea = r.Overhangs, r.OverhangArea
+	model.Shells, model.Hidden = r.Shells, make([]bool, len(r.Shells))
+}
+
+// findAnalysis analyzes model in the background and sends the result on
+// ch
+func findAnalysis(ctx context.Context, model *Model, overhang OverhangOptions, ch chan analysisResult) {
+	go func() {
+		start := time.Now()
+		r, err := analyzeModel(ctx, model, overhang)
+		if err != nil {
+			return
+		}
+		log.Printf("analyzed in %.3f seconds\n", time.Since(start).Seconds())
+		select {
+		case ch <- r:
+			wake()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// intersectionResult carries the self-intersections found for a model
+type intersectionResult struct {
+	Model *Model
+	Pairs [][2]int
+}
+
+// findIntersections finds the self-intersections of model in the
+// background and sends them on ch
+func findIntersections(ctx context.Context, model *Model, ch chan intersectionResult) {
+	go func() {
+		start := time.Now()
+		pairs := SelfIntersections(model.Data)
+		log.Printf("found %d intersecting pairs in %.3f seconds\n", len(pairs), time.Since(start).Seconds())
+		if pairs == nil {
+			pairs = [][2]int{}
+		}
+		select {
+		case ch <- intersectionResult{model, pairs}:
+			wake()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// thicknessResult carries the wall thickness measured for a model
+type thicknessResult struct {
+	Model     *Model
+	Thickness []float64
+	Report    ThicknessReport
+}
+
+// findThickness measures the wall thickness of model in the background,
+// summarizing the walls thinner than minimum
+func findThickness(ctx context.Context, model *Model, minimum float64, ch chan thicknessResult) {
+	go func() {
+		start := time.Now()
+		data := model.Data
+		thickness := Thickness(data)
+		report := SummarizeThickness(data, thickness, minimum)
+		log.Printf("measured thickness in %.3f seconds, %d regions thinner than %g\n",
+			time.Since(start).Seconds(), len(report.Regions), report.Minimum)
+		select {
+		case ch <- thicknessResult{model, thickness, report}:
+			wake()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// LodTarget is the triangle count meshes are decimated to for drawing
+// while the view moves unless ViewerOptions say otherwise; larger meshes
+// get a proxy
+var LodTarget = 250000
+
+// lodIdle is how long the view must be still before the full mesh is drawn
+const lodIdle = 300 * time.Millisecond
+
+// chunkBudget is about the most triangles uploaded each frame, so the
+// first frames of a large model appear while the rest is on its way
+const chunkBudget = 1 << 20
+
+// proxyResult carries the decimated proxy built for a model, with the
+// triangle of the mesh each of its own came from
+type proxyResult struct {
+	Model  *Model
+	Buffer []float32
+	Faces  []int
+}
+
+// buildProxy decimates model's indexed mesh to target triangles in the
+// background
+func buildProxy(ctx context.Context, model *Model, target int, ch chan proxyResult) {
+	go func() {
+		start := time.Now()
+		proxy, faces := DecimateIndexed(model.Indexed, target)
+		log.Printf("decimated to %d triangles in %.3f seconds\n", len(proxy.Buffer)/9, time.Since(start).Seconds())
+		select {
+		case ch <- proxyResult{model, proxy.Buffer, faces}:
+			wake()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// frameIntersection points the arcball at the current intersecting pair
+func frameIntersection(interactor Interactor, model *Model) {
+	a, ok := interactor.(*Arcball)
+	if !ok || len(model.Intersections) == 0 {
+		return
 	}
-	window.Maximize()
-	window.MakeContextCurrent()
+	pair := model.Intersections[model.Intersection]
+	box := model.Data.Triangle(pair[0]).BoundingBox().Extend(model.Data.Triangle(pair[1]).BoundingBox())
+	center := model.Transform.MulPosition(box.Center())
+	radius := model.Transform.MulPosition(box.Max).Distance(center)
+	a.Frame(center, radius)
+}
 
-	fmt.Printf("window shown at %.3f seconds\n", time.Since(start).Seconds())
+// repairResult is a repaired model and the model it replaces
+type repairResult struct {
+	Model    *Model
+	Repaired *Model
+}
 
-	// initialize gl
-	if err := gl.Init(); err != nil {
-		panic(err)
+// repairModel repairs the mesh of model in the background, saves it beside
+// the original and sends the repaired model on ch
+func repairModel(ctx context.Context, model *Model, overhang OverhangOptions, ch chan repairResult) {
+	go func() {
+		data, report := Repair(model.Data, DefaultRepairOptions(model.Data))
+		report.Print(os.Stdout)
+		path := strings.TrimSuffix(model.Path, filepath.Ext(model.Path)) + ".repaired.stl"
+		if err := data.SaveSTL(path); err != nil {
+			log.Println("save error", err)
+		} else {
+			log.Println("saved repaired mesh to", path)
+		}
+		repaired := NewModel(data)
+		repaired.Path = path
+		r, err := analyzeModel(ctx, repaired, overhang)
+		if err != nil {
+			return
+		}
+		r.apply()
+		select {
+		case ch <- repairResult{model, repaired}:
+			wake()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// drawLayer fills a layer by the even-odd rule using the stencil buffer,
+// then outlines its outer contours and holes in their own colors
+func drawLayer(colorAttrib uint32, layer slicer.Layer, contours []*Contour, repair LayerRepair) {
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.CULL_FACE)
+
+	// invert the stencil under each path's fan, leaving its interior odd
+	gl.Enable(gl.STENCIL_TEST)
+	gl.ColorMask(false, false, false, false)
+	gl.StencilFunc(gl.ALWAYS, 0, 1)
+	gl.StencilOp(gl.KEEP, gl.KEEP, gl.INVERT)
+	for _, path := range layer.Paths {
+		gl.Begin(gl.TRIANGLE_FAN)
+		for _, v := range path {
+			gl.Vertex3f(float32(v.X), float32(v.Y), float32(v.Z))
+		}
+		gl.End()
+	}
+
+	// cover the interior, zeroing the stencil again as we go
+	gl.ColorMask(true, true, true, true)
+	gl.StencilFunc(gl.NOTEQUAL, 0, 1)
+	gl.StencilOp(gl.ZERO, gl.ZERO, gl.ZERO)
+	setColor(colorAttrib, fillColor)
+	x0, y0, x1, y1 := layerBounds([]slicer.Layer{layer})
+	z := float32(layer.Z)
+	gl.Begin(gl.QUADS)
+	gl.Vertex3f(float32(x0), float32(y0), z)
+	gl.Vertex3f(float32(x1), float32(y0), z)
+	gl.Vertex3f(float32(x1), float32(y1), z)
+	gl.Vertex3f(float32(x0), float32(y1), z)
+	gl.End()
+	gl.Disable(gl.STENCIL_TEST)
+
+	var outline func([]*Contour)
+	outline = func(contours []*Contour) {
+		for _, c := range contours {
+			if c.Hole {
+				setColor(colorAttrib, holeColor)
+			} else {
+				setColor(colorAttrib, outerColor)
+			}
+			gl.Begin(gl.LINE_STRIP)
+			for _, v := range c.Path {
+				gl.Vertex3f(float32(v.X), float32(v.Y), float32(v.Z))
+			}
+			gl.End()
+			outline(c.Children)
+		}
 	}
+	outline(contours)
 
-	// MGD
-	//gl.Enable(gl.BLEND)
-	//gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
-	
-	glfw.SwapInterval(1)
+	// mark the loose ends of unrepaired paths
+	setColor(colorAttrib, defectColor)
+	gl.PointSize(6)
+	gl.Begin(gl.POINTS)
+	for _, gap := range repair.Gaps {
+		gl.Vertex3f(float32(gap.Start.X), float32(gap.Start.Y), float32(gap.Start.Z))
+		gl.Vertex3f(float32(gap.End.X), float32(gap.End.Y), float32(gap.End.Z))
+	}
+	gl.End()
 
+	gl.Enable(gl.CULL_FACE)
 	gl.Enable(gl.DEPTH_TEST)
+}
+
+// drawEdges draws edges as lines in color
+func drawEdges(colorAttrib uint32, edges []Edge, color fauxgl.Vector) {
+	setColor(colorAttrib, color)
+	gl.Begin(gl.LINES)
+	for _, e := range edges {
+		gl.Vertex3f(float32(e[0].X), float32(e[0].Y), float32(e[0].Z))
+		gl.Vertex3f(float32(e[1].X), float32(e[1].Y), float32(e[1].Z))
+	}
+	gl.End()
+}
+
+// drawProblems draws boundary, non-manifold and badly wound edges in bright
+// colors, visible through the model
+func drawProblems(colorAttrib uint32, a *Analysis) {
+	gl.Disable(gl.DEPTH_TEST)
+	gl.LineWidth(3)
+	drawEdges(colorAttrib, a.Boundary, defectColor)
+	drawEdges(colorAttrib, a.NonManifold, nonManifoldColor)
+	drawEdges(colorAttrib, a.Winding, windingColor)
+	gl.LineWidth(1)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// drawTriangle draws one mesh triangle
+func drawTriangle(t *fauxgl.Triangle) {
+	for _, v := range []fauxgl.Vector{t.V1.Position, t.V2.Position, t.V3.Position} {
+		gl.Vertex3f(float32(v.X), float32(v.Y), float32(v.Z))
+	}
+}
+
+// drawIntersections draws the self-intersecting triangles of model in red
+// over the mesh, with the current pair in yellow
+func drawIntersections(colorAttrib uint32, model *Model) {
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(-1, -1)
+	gl.Disable(gl.CULL_FACE)
+	setColor(colorAttrib, defectColor)
+	gl.Begin(gl.TRIANGLES)
+	for i, pair := range model.Intersections {
+		if i != model.Intersection {
+			drawTriangle(model.Data.Triangle(pair[0]))
+			drawTriangle(model.Data.Triangle(pair[1]))
+		}
+	}
+	gl.End()
+	if model.Intersection < len(model.Intersections) {
+		pair := model.Intersections[model.Intersection]
+		setColor(colorAttrib, windingColor)
+		gl.Begin(gl.TRIANGLES)
+		drawTriangle(model.Data.Triangle(pair[0]))
+		drawTriangle(model.Data.Triangle(pair[1]))
+		gl.End()
+	}
 	gl.Enable(gl.CULL_FACE)
-	gl.CullFace(gl.BACK)
-	gl.ClearColor(float32(0xd4)/255, float32(0xd9)/255, float32(0xde)/255, 1)
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+}
 
-	// compile shaders
-	program, err := compileProgram(vertexShader, fragmentShader)
+// drawSlider draws the layers as a bar down the right of the window, with
+// defective layers marked in red and the current layer in black
+func drawSlider(matrixUniform int32, colorAttrib uint32, model *Model, index int) {
+	n := len(model.Slices)
+	if n == 0 {
+		return
+	}
+	y := func(i int) float32 {
+		if n == 1 {
+			return 0
+		}
+		return -0.9 + 1.8*float32(i)/float32(n-1)
+	}
+	setMatrix(matrixUniform, fauxgl.Identity())
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Begin(gl.LINES)
+	setColor(colorAttrib, outerColor)
+	gl.Vertex3f(0.95, -0.9, 0)
+	gl.Vertex3f(0.95, 0.9, 0)
+	gl.End()
+	setColor(colorAttrib, defectColor)
+	gl.Begin(gl.LINES)
+	for i, r := range model.Repairs {
+		if r.Defective() {
+			gl.Vertex3f(0.93, y(i), 0)
+			gl.Vertex3f(0.97, y(i), 0)
+		}
+	}
+	gl.End()
+	setColor(colorAttrib, outerColor)
+	gl.Begin(gl.QUADS)
+	gl.Vertex3f(0.94, y(index)-0.005, 0)
+	gl.Vertex3f(0.96, y(index)-0.005, 0)
+	gl.Vertex3f(0.96, y(index)+0.005, 0)
+	gl.Vertex3f(0.94, y(index)+0.005, 0)
+	gl.End()
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// drawLegend draws the deviation color ramp down the left of the window,
+// with a tick at zero
+func drawLegend(matrixUniform int32, colorAttrib uint32) {
+	const stops = 16
+	setMatrix(matrixUniform, fauxgl.Identity())
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Begin(gl.QUAD_STRIP)
+	for i := 0; i <= stops; i++ {
+		t := float64(i) / stops
+		y := float32(-0.9 + 1.8*t)
+		setColor(colorAttrib, Ramp(t))
+		gl.Vertex3f(-0.97, y, 0)
+		gl.Vertex3f(-0.94, y, 0)
+	}
+	gl.End()
+	setColor(colorAttrib, outerColor)
+	gl.Begin(gl.LINES)
+	gl.Vertex3f(-0.98, 0, 0)
+	gl.Vertex3f(-0.93, 0, 0)
+	gl.End()
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// drawProgress draws a bar along the bottom of the window
+func drawProgress(matrixUniform int32, progress float64) {
+	x0 := float32(-0.9)
+	x1 := x0 + 1.8*float32(progress)
+	y0 := float32(-0.95)
+	y1 := float32(-0.93)
+	setMatrix(matrixUniform, fauxgl.Identity())
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Begin(gl.QUADS)
+	gl.Vertex3f(x0, y0, 0)
+	gl.Vertex3f(x1, y0, 0)
+	gl.Vertex3f(x1, y1, 0)
+	gl.Vertex3f(x0, y1, 0)
+	gl.End()
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+var meshColor = fauxgl.V(0x5b/255.0, 0xac/255.0, 0xe3/255.0)
+var fillColor = fauxgl.V(0.8, 0.85, 0.9)
+var outerColor = fauxgl.V(0.1, 0.1, 0.1)
+var holeColor = fauxgl.V(0.9, 0.5, 0.1)
+var defectColor = fauxgl.V(1, 0, 0)
+var nonManifoldColor = fauxgl.V(1, 0, 1)
+var windingColor = fauxgl.V(1, 1, 0)
+
+// RunDiff opens the viewer on the mesh at path, aligned with the mesh at
+// other per alignment and shaded by its deviation from it
+func RunDiff(path, other string, alignment Alignment) {
+	RunWith(ViewerOptions{Compare: other, Alignment: alignment, Shade: ShadeDeviation}, path)
+}
+
+// Run opens the viewer on the meshes at paths, if any, in their original
+// coordinates until the window is closed
+func Run(paths ...string) {
+	RunWith(ViewerOptions{}, paths...)
+}
+
+// RunScene opens the viewer on the meshes at paths placed per layout
+func RunScene(layout Layout, paths ...string) {
+	RunWith(ViewerOptions{Layout: layout}, paths...)
+}
+
+// RunWith opens a viewer configured by opts on the meshes at paths, if any,
+// until the window is closed
+func RunWith(opts ViewerOptions, paths ...string) {
+	start := time.Now()
+	if opts.Title == "" {
+		opts.Title = strings.Join(paths, " ")
+	}
+	v, err := NewViewer(opts)
 	if err != nil {
 		panic(err)
 	}
-	gl.UseProgram(program)
+	fmt.Printf("window shown at %.3f seconds\n", time.Since(start).Seconds())
+	if len(paths) > 0 {
+		v.Load(paths...)
+	}
+	v.Run()
+}
 
-	matrixUniform := uniformLocation(program, "matrix")
-	//positionAttrib := attribLocation(program, "position")
+// updateShade replaces the model's per vertex colors with those of mode
+func updateShade(model *Model, mode ShadeMode) {
+	if model.ShadeBuf != 0 {
+		if buf, ok := model.ProxyShadeBufs[model.ShadeBuf]; ok {
+			gl.DeleteBuffers(1, &buf)
+			delete(model.ProxyShadeBufs, model.ShadeBuf)
+		}
+		gl.DeleteBuffers(1, &model.ShadeBuf)
+		model.ShadeBuf = 0
+	}
+	model.ShadeBuf = uploadShade(model, mode)
+	model.Shade = mode
+}
 
-	var model *Model
+// uploadShade uploads the model's colors in mode, with the vaos they are
+// drawn with, returning the color buffer or 0 if mode has none
+func uploadShade(model *Model, mode ShadeMode) uint32 {
+	if mode == ShadeShells && model.ShellVao.Len == 0 && model.Shells != nil {
+		model.ShellVao = NewVao(ShellBuffer(model.Data, model.Shells))
+		PrintShells(os.Stdout, model.Shells)
+	}
+	colors := model.ShadeColors(mode)
+	if colors == nil {
+		return 0
+	}
+	if model.ShadeVao.Len == 0 {
+		model.ShadeVao = NewVao(model.Data.Buffer)
+	}
+	return NewColorBuffer(colors)
+}
 
-	// create interactor
-	interactor := NewArcball()
-	BindInteractor(window, interactor)
+// shadeBuffer returns the buffer of the model's colors in mode, uploading
+// it the first time a viewport asks for a mode other than the model's
+func shadeBuffer(model *Model, mode ShadeMode) uint32 {
+	if mode == model.Shade {
+		return model.ShadeBuf
+	}
+	if buf, ok := model.ShadeBufs[mode]; ok {
+		return buf
+	}
+	buf := uploadShade(model, mode)
+	if buf != 0 {
+		if model.ShadeBufs == nil {
+			model.ShadeBufs = map[ShadeMode]uint32{}
+		}
+		model.ShadeBufs[mode] = buf
+	}
+	return buf
+}
 
-	// Get supported line width range and step size
-	var lineWidthSizes [2]float32
-	gl.GetFloatv(gl.LINE_WIDTH_RANGE, &lineWidthSizes[0])
-	var lineWidthStep float32
-	gl.GetFloatv(gl.LINE_WIDTH_GRANULARITY, &lineWidthStep)
-	log.Println("lws", lineWidthSizes, "lwstep", lineWidthStep)
-	gl.LineWidth(1)
-	gl.Disable(gl.LINE_STIPPLE)
-	gl.Enable(gl.LINE_SMOOTH)
-	gl.Hint(gl.LINE_SMOOTH_HINT,  gl.NICEST)
-
-	// render function
-	// MGD test not redrawing if no change
-	render := func() {
-		// WAS gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
-		if model != nil {
-			matrix := getMatrix(window, interactor, model)
-			// MGD
-			if matrix != lastMatrix {
-				lastMatrix = matrix
-				gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
-				setMatrix(matrixUniform, matrix.Translate(fauxgl.V(-0.5,0,0)))
-				model.MeshVao.Draw()
-				// // box the model
-				// a := float32(model.Mesh.BoundingBox().Min.MinComponent())
-				// b := float32(model.Mesh.BoundingBox().Max.MaxComponent())
-				// gl.Begin(gl.LINE_STRIP)
-				// gl.Vertex3f(a,a,a)
-				// gl.Vertex3f(a,a,b)
-				// gl.Vertex3f(b,a,b)
-				// gl.Vertex3f(b,a,a)
-				// gl.Vertex3f(a,a,a)
-				// gl.Vertex3f(a,b,a)
-				// gl.Vertex3f(a,b,b)
-				// gl.Vertex3f(b,b,b)
-				// gl.Vertex3f(b,b,a)
-				// gl.Vertex3f(a,b,a)
-				// gl.End()
-
-				setMatrix(matrixUniform, matrix.Translate(fauxgl.V(0.5, 0, 0)))
-				slice := model.Slices[sliceIndex]
-				for _, path := range slice.Paths {
-					gl.Begin(gl.LINE_STRIP)
-					for _, v := range path {
-						gl.Vertex3f(float32(v.X), float32(v.Y), float32(v.Z))
-					}
-					gl.End()
-				}
-
-				// vaos := model.SliceVaos[sliceIndex]
-				// for _, vao := range vaos {
-				// 	vao.DrawLineStrip()
-				// }
-
-				// for _, vaos := range model.SliceVaos {
-				// 	for _, vao := range vaos {
-				// 		vao.DrawLines()
-				// 	}
-				// 	break
-				// }
-				// MGD
-				// if mesh.SliceBuffer > 0 {
-				// 	gl.BindBuffer(gl.ARRAY_BUFFER, mesh.SliceBuffer)
-				// 	gl.EnableVertexAttribArray(positionAttrib)
-				// 	gl.VertexAttribPointer(positionAttrib, 3, gl.FLOAT, false, 0, gl.PtrOffset(0))
-				// 	setMatrix(matrixUniform, matrix.Translate(fauxgl.V(0.5, 0, 0)))
-				// 	gl.DrawArrays(gl.LINES, 0, mesh.SliceCount)
-				// 	gl.DisableVertexAttribArray(positionAttrib)
-				// 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-				// }
-				window.SwapBuffers()
+// proxyShadeBuffer returns the colors of the model's proxy matching its
+// shade buffer buf in mode, taken from the triangles the proxy's came from
+// the first time they are drawn, or 0 if there are none
+func proxyShadeBuffer(model *Model, mode ShadeMode, buf uint32) uint32 {
+	if proxy, ok := model.ProxyShadeBufs[buf]; ok {
+		return proxy
+	}
+	colors := model.ShadeColors(mode)
+	if colors == nil {
+		return 0
+	}
+	proxyColors := make([]float32, 0, len(model.ProxyFaces)*9)
+	for _, i := range model.ProxyFaces {
+		proxyColors = append(proxyColors, colors[i*9:i*9+9]...)
+	}
+	proxy := NewColorBuffer(proxyColors)
+	if model.ProxyShadeBufs == nil {
+		model.ProxyShadeBufs = map[uint32]uint32{}
+	}
+	model.ProxyShadeBufs[buf] = proxy
+	return proxy
+}
+
+// drawModel draws the model's mesh, or its decimated proxy if asked,
+// shaded in mode if it has colors in it or else in color; plain meshes are
+// culled to the view of matrix
+func drawModel(colorAttrib uint32, model *Model, mode ShadeMode, color fauxgl.Vector, proxy bool, matrix fauxgl.Matrix) {
+	buf := shadeBuffer(model, mode)
+	if mode == ShadeShells && model.ShellVao.Len != 0 {
+		drawShells(colorAttrib, model)
+		return
+	}
+	if buf != 0 {
+		if proxy {
+			if colors := proxyShadeBuffer(model, mode, buf); colors != 0 {
+				model.ProxyVao.DrawColors(colors, colorAttrib)
+				return
 			}
-			// MGD
-			//layer := slicer.SliceMesh(mesh, 0.1)
-			// WAS
-			// setMatrix(matrixUniform, matrix)
-			// mesh.Draw(positionAttrib)
+		}
+		model.ShadeVao.DrawColors(buf, colorAttrib)
+		return
+	}
+	setColor(colorAttrib, color)
+	if proxy {
+		model.ProxyVao.Draw()
+		return
+	}
+	model.DrawVisible(matrix)
+}
 
+// drawShells draws each visible shell of model in its own color, boxing
+// the selected one
+func drawShells(colorAttrib uint32, model *Model) {
+	var first int32
+	for i, shell := range model.Shells {
+		count := int32(len(shell.Triangles) * 3)
+		if !model.Hidden[i] {
+			setColor(colorAttrib, Palette(i))
+			model.ShellVao.DrawRange(first, count)
 		}
-		//window.SwapBuffers()
+		first += count
 	}
+	if model.Shell >= len(model.Shells) {
+		return
+	}
+	b := model.Shells[model.Shell].Box
+	corner := func(i int) fauxgl.Vector {
+		p := [2]fauxgl.Vector{b.Min, b.Max}
+		return fauxgl.V(p[i&1].X, p[i>>1&1].Y, p[i>>2&1].Z)
+	}
+	setColor(colorAttrib, outerColor)
+	gl.Begin(gl.LINES)
+	for i := 0; i < 8; i++ {
+		for _, bit := range []int{1, 2, 4} {
+			if i&bit == 0 {
+				p, q := corner(i), corner(i|bit)
+				gl.Vertex3f(float32(p.X), float32(p.Y), float32(p.Z))
+				gl.Vertex3f(float32(q.X), float32(q.Y), float32(q.Z))
+			}
+		}
+	}
+	gl.End()
+}
 
-	// render during resize
-	window.SetFramebufferSizeCallback(func(window *glfw.Window, w, h int) {
-		//width, height := window.GetFramebufferSize()
-		//log.Println("resizing", width, height)
-		render()
-	})
+// exportShell saves shell i of model as an stl beside the model's file
+func exportShell(model *Model, i int) {
+	base := strings.TrimSuffix(model.Path, filepath.Ext(model.Path))
+	path := fmt.Sprintf("%s.shell%03d.stl", base, i+1)
+	data := model.Data.Subset(model.Shells[i].Triangles)
+	if err := data.SaveSTL(path); err != nil {
+		log.Println("export error", err)
+		return
+	}
+	log.Println("exported shell", i+1, "to", path)
+}
 
-	// handle drop events
-	window.SetDropCallback(func(window *glfw.Window, filenames []string) {
-		loadModel(filenames[0], ch)
-		window.SetTitle(filenames[0])
-	})
+// memoryInterval is how often the title's memory usage is sampled, as
+// reading it briefly stops the program
+const memoryInterval = time.Second
 
-	// main loop
-	for !window.ShouldClose() {
-		select {
-		case newModel := <-ch:
-			if model != nil {
-				model.Destroy()
-			}
-			model = newModel
-			model.MeshVao = Triangles2Vao(model.Mesh.Triangles)
-			sliceIndex = 0
-			sliceMax = len(model.Slices)-1
-
-			for _, slice := range model.Slices {
-				vaos := []Vao{}
-				for _, p := range slice.Paths {
-					vaos = append(vaos, Vectors2Vao(p))
-				}
-				model.SliceVaos = append(model.SliceVaos, vaos)
-			}
-			
-			//log.Printf("first frame at %.3f seconds\n", time.Since(start).Seconds())
-			//mesh.Slice((data.Box.Min.Z+data.Box.Max.Z)*0.1)
-			//fmt.Printf("sliced at %.3f seconds\n", time.Since(start).Seconds())
-		default:
+// memoryUsage returns the bytes the program holds from the system, less
+// those returned unused
+func memoryUsage() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys - m.HeapReleased
+}
+
+// formatBytes formats n bytes in binary units
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// modelTitle describes the model and its current layer
+func (v *Viewer) modelTitle(model *Model) string {
+	sliceIndex := v.sliceIndex
+	title := fmt.Sprintf("%s (volume %.3f area %.3f)", model.Path, model.Mass.Volume, model.Mass.Area)
+	if model.Indexed == nil {
+		title += " - analyzing"
+	} else if len(model.ChunkVaos) < len(model.Chunks) {
+		title += fmt.Sprintf(" - uploading %.0f%%", model.UploadProgress()*100)
+	}
+	if model.Sliced < len(model.Slices) {
+		title += fmt.Sprintf(" - slicing %.0f%%", model.SliceProgress()*100)
+	} else if sliceIndex < len(model.Slices) {
+		s := model.Stats[sliceIndex]
+		title += fmt.Sprintf(" - layer %d/%d z %.3f area %.3f perimeter %.3f islands %d holes %d",
+			sliceIndex+1, len(model.Slices), model.Slices[sliceIndex].Z,
+			s.Area, s.Perimeter, s.Islands, s.Holes)
+		if gaps := len(model.Repairs[sliceIndex].Gaps); gaps > 0 {
+			title += fmt.Sprintf(" gaps %d", gaps)
 		}
-		render()
-		glfw.PollEvents()
 	}
+	if v.shadeMode == ShadeOverhang {
+		title += fmt.Sprintf(" - overhang area %.3f", model.OverhangArea)
+	}
+	if v.shadeMode == ShadeShells && model.Shell < len(model.Shells) {
+		shell := model.Shells[model.Shell]
+		size := shell.Box.Size()
+		title += fmt.Sprintf(" - shell %d/%d triangles %d volume %.3f size %.3g x %.3g x %.3g",
+			model.Shell+1, len(model.Shells), len(shell.Triangles), shell.Volume, size.X, size.Y, size.Z)
+		if model.Hidden[model.Shell] {
+			title += " (hidden)"
+		}
+	}
+	if v.shadeMode == ShadeDeviation && model.Deviation != nil {
+		r := model.Compare
+		title += fmt.Sprintf(" - vs %s max %.4f mean %.4f rms %.4f hausdorff %.4f, legend ±%.4g",
+			filepath.Base(model.ComparePath), r.Scale(), r.Mean, r.RMS, r.Hausdorff, model.DeviationScale())
+	}
+	if v.shadeMode == ShadeThickness {
+		if model.Thickness == nil {
+			title += " - measuring thickness"
+		} else {
+			title += fmt.Sprintf(" - thinnest %.3f, %d regions below %g area %.3f",
+				model.Thin.Thinnest, len(model.Thin.Regions), model.Thin.Minimum, model.Thin.Area)
+		}
+	}
+	if n := len(v.models); n > 1 {
+		title += fmt.Sprintf(" - model %d/%d", v.active+1, n)
+		if !model.Visible {
+			title += " (hidden)"
+		}
+	}
+	if v.showIntersections {
+		if model.Intersections == nil {
+			title += " - finding intersections"
+		} else if len(model.Intersections) == 0 {
+			title += " - no intersections"
+		} else {
+			title += fmt.Sprintf(" - intersection %d/%d", model.Intersection+1, len(model.Intersections))
+		}
+	}
+	return title
+}
+
+// loadingTitle describes the files loading, while they are previewed
+func (v *Viewer) loadingTitle() string {
+	if len(v.loading) == 1 {
+		for _, p := range v.loading {
+			return fmt.Sprintf("%s - loading %.0f%%", p.Path, p.Progress*100)
+		}
+	}
+	return fmt.Sprintf("loading %d files %.0f%%", len(v.loading), v.loadingProgress()*100)
+}
+
+// loadingProgress returns the mean progress of the files loading
+func (v *Viewer) loadingProgress() float64 {
+	if len(v.loading) == 0 {
+		return 1
+	}
+	var total float64
+	for _, p := range v.loading {
+		total += p.Progress
+	}
+	return total / float64(len(v.loading))
 }
 
-func getMatrix(window *glfw.Window, interactor Interactor, model *Model) fauxgl.Matrix {
-	return interactor.Matrix(window).Mul(model.Transform)
+// exportLayer saves layer i of model as an svg beside the model's file
+func exportLayer(model *Model, i int) {
+	base := strings.TrimSuffix(model.Path, filepath.Ext(model.Path))
+	path := fmt.Sprintf("%s.layer%04d.svg", base, i)
+	if err := SaveLayers(path, model.Slices[i:i+1]); err != nil {
+		log.Println("export error", err)
+		return
+	}
+	log.Println("exported layer", i, "to", path)
 }
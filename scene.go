@@ -0,0 +1,85 @@
+package meshview
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Layout chooses where the models of a scene are placed
+type Layout int
+
+// Models stay where their files put them, as mating parts are modeled, or
+// are lined up along X in load order with their bottoms level
+const (
+	LayoutOriginal Layout = iota
+	LayoutSideBySide
+)
+
+// ParseLayout parses "original" or "side"
+func ParseLayout(s string) (Layout, error) {
+	switch s {
+	case "original":
+		return LayoutOriginal, nil
+	case "side":
+		return LayoutSideBySide, nil
+	}
+	return LayoutOriginal, fmt.Errorf("unknown layout %q", s)
+}
+
+// layoutGap is the space left between models laid out side by side, as a
+// fraction of the largest
+const layoutGap = 0.1
+
+// ArrangeModels places models per layout, setting each Placement, then
+// points every Transform at the box of them all so they share one view
+func ArrangeModels(models []*Model, layout Layout) {
+	if len(models) == 0 {
+		return
+	}
+	boxes := make([]fauxgl.Box, len(models))
+	gap := 0.0
+	for i, model := range models {
+		boxes[i] = modelBox(model)
+		gap = math.Max(gap, boxes[i].Size().MaxComponent()*layoutGap)
+	}
+	x := 0.0
+	for i, model := range models {
+		if layout != LayoutSideBySide {
+			model.Placement = fauxgl.Identity()
+			continue
+		}
+		box := boxes[i]
+		offset := fauxgl.V(x-box.Min.X, -box.Center().Y, -box.Min.Z)
+		model.Placement = fauxgl.Translate(offset)
+		x += box.Size().X + gap
+	}
+	scene := SceneBox(models)
+	for _, model := range models {
+		model.Transform = BoxTransform(scene).Mul(model.Placement)
+	}
+}
+
+// SceneBox returns the box holding models where they are placed
+func SceneBox(models []*Model) fauxgl.Box {
+	var scene fauxgl.Box
+	for i, model := range models {
+		box := modelBox(model).Transform(model.Placement)
+		if i == 0 {
+			scene = box
+		} else {
+			scene = scene.Extend(box)
+		}
+	}
+	return scene
+}
+
+// modelBox returns the box of the model's mesh, from the indexed copy if
+// it has one since that is already measured
+func modelBox(model *Model) fauxgl.Box {
+	if model.Indexed != nil {
+		return model.Indexed.Box
+	}
+	return model.Data.Box
+}
@@ -0,0 +1,67 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func boxModel(min, max fauxgl.Vector) *Model {
+	return NewModel(FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(min, max))))
+}
+
+func TestParseLayout(t *testing.T) {
+	for s, want := range map[string]Layout{"original": LayoutOriginal, "side": LayoutSideBySide} {
+		if got, err := ParseLayout(s); err != nil || got != want {
+			t.Errorf("%q parsed as %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseLayout("grid"); err == nil {
+		t.Errorf("unknown layout parsed")
+	}
+}
+
+func TestArrangeOriginal(t *testing.T) {
+	a := boxModel(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1))
+	b := boxModel(fauxgl.V(1, 0, 0), fauxgl.V(3, 1, 1))
+	models := []*Model{a, b}
+	ArrangeModels(models, LayoutOriginal)
+	box := SceneBox(models)
+	if box.Min != fauxgl.V(0, 0, 0) || box.Max != fauxgl.V(3, 1, 1) {
+		t.Errorf("scene box %v, want the union of the meshes", box)
+	}
+	// both share the transform fitting the scene to the view
+	if a.Transform != b.Transform || a.Transform != BoxTransform(box) {
+		t.Errorf("models are not fitted to the scene together")
+	}
+}
+
+func TestArrangeSideBySide(t *testing.T) {
+	a := boxModel(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))
+	b := boxModel(fauxgl.V(-1, 5, 3), fauxgl.V(1, 6, 4))
+	c := boxModel(fauxgl.V(0, 0, 0), fauxgl.V(2, 2, 2))
+	models := []*Model{a, b, c}
+	ArrangeModels(models, LayoutSideBySide)
+	var boxes []fauxgl.Box
+	for _, m := range models {
+		box := m.Data.Box.Transform(m.Placement)
+		if box.Min.Z != 0 {
+			t.Errorf("bottom at %g, want 0", box.Min.Z)
+		}
+		if y := box.Center().Y; y != 0 {
+			t.Errorf("centered at y %g, want 0", y)
+		}
+		boxes = append(boxes, box)
+	}
+	for i := 1; i < len(boxes); i++ {
+		if boxes[i].Min.X <= boxes[i-1].Max.X {
+			t.Errorf("model %d at x %g overlaps the one before, ending at %g", i, boxes[i].Min.X, boxes[i-1].Max.X)
+		}
+	}
+	ArrangeModels(models, LayoutOriginal)
+	for _, m := range models {
+		if m.Placement != fauxgl.Identity() {
+			t.Errorf("placement %v kept in original layout", m.Placement)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package meshview
+
+// ShadeMode chooses how the mesh faces are colored
+type ShadeMode int
+
+// The mesh is a single color unless shaded by an analysis
+const (
+	ShadeSolid ShadeMode = iota
+	ShadeOverhang
+	ShadeThickness
+	ShadeDeviation
+	ShadeShells
+)
+
+// ShadeColors returns the per vertex colors of the model's mesh in mode,
+// or nil if mode is solid, colors whole shells, or its analysis is missing
+func (model *Model) ShadeColors(mode ShadeMode) []float32 {
+	switch mode {
+	case ShadeOverhang:
+		if model.Overhangs != nil {
+			return OverhangFaceColors(model.Overhangs)
+		}
+	case ShadeThickness:
+		if model.Thickness != nil {
+			return ThicknessFaceColors(model.Thickness, model.Thin.Minimum)
+		}
+	case ShadeDeviation:
+		if model.Deviation != nil {
+			return DeviationColors(model.Deviation, model.DeviationScale())
+		}
+	}
+	return nil
+}
+
+// DeviationScale returns the deviation the model's heatmap spans either
+// side of zero
+func (model *Model) DeviationScale() float64 {
+	if model.DeviationLimit > 0 {
+		return model.DeviationLimit
+	}
+	return model.Compare.Scale()
+}
@@ -18,6 +18,11 @@ func setMatrix(location int32, m fauxgl.Matrix) {
 	gl.UniformMatrix4fv(location, 1, true, &data[0])
 }
 
+// setColor sets the color used by vertices without a color array
+func setColor(location uint32, c fauxgl.Vector) {
+	gl.VertexAttrib3f(location, float32(c.X), float32(c.Y), float32(c.Z))
+}
+
 func uniformLocation(program uint32, name string) int32 {
 	return gl.GetUniformLocation(program, gl.Str(name+"\x00"))
 }
@@ -40,6 +45,8 @@ func compileProgram(vertexShaderSource, fragmentShaderSource string) (uint32, er
 	program := gl.CreateProgram()
 	gl.AttachShader(program, vertexShader)
 	gl.AttachShader(program, fragmentShader)
+	// immediate mode vertices go to attribute 0
+	gl.BindAttribLocation(program, 0, gl.Str("position\x00"))
 	gl.LinkProgram(program)
 
 	var status int32
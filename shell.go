@@ -0,0 +1,69 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// Shell is a connected part of a mesh
+type Shell struct {
+	Triangles []int // indices into the mesh's triangles
+	Volume    float64
+	Area      float64
+	Box       fauxgl.Box
+}
+
+// FindShells splits data into the connected components of its welded
+// triangle graph, in order of their first triangle
+func FindShells(data *MeshData) []Shell {
+	vertices, indices := Weld(data.Buffer, WeldTolerance(data.Box))
+	labels := ComponentLabels(indices, len(vertices))
+	shells := make([]Shell, countLabels(labels))
+	for i, l := range labels {
+		shells[l].Triangles = append(shells[l].Triangles, i)
+	}
+	for i := range shells {
+		s := &shells[i]
+		part := data.Subset(s.Triangles)
+		p := part.MassProperties(0)
+		s.Volume = p.Volume
+		s.Area = p.Area
+		s.Box = part.Box
+	}
+	return shells
+}
+
+// PrintShells writes a line for each shell
+func PrintShells(w io.Writer, shells []Shell) {
+	for i, s := range shells {
+		size := s.Box.Size()
+		fmt.Fprintf(w, "shell %-4d triangles %-8d volume %-12g size %g %g %g\n",
+			i+1, len(s.Triangles), s.Volume, size.X, size.Y, size.Z)
+	}
+}
+
+// Subset returns the given triangles of data as a mesh of their own
+func (data *MeshData) Subset(triangles []int) *MeshData {
+	buffer := make([]float32, 0, len(triangles)*9)
+	for _, i := range triangles {
+		buffer = append(buffer, data.Buffer[i*9:i*9+9]...)
+	}
+	if len(buffer) == 0 {
+		return &MeshData{buffer, fauxgl.Box{}, nil}
+	}
+	return &MeshData{buffer, boxForData(buffer), nil}
+}
+
+// ShellBuffer returns the triangles of data grouped by shell, in order, so
+// each shell can be drawn as one range
+func ShellBuffer(data *MeshData, shells []Shell) []float32 {
+	buffer := make([]float32, 0, len(data.Buffer))
+	for _, s := range shells {
+		for _, i := range s.Triangles {
+			buffer = append(buffer, data.Buffer[i*9:i*9+9]...)
+		}
+	}
+	return buffer
+}
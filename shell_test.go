@@ -0,0 +1,38 @@
+package meshview
+
+import (
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestFindShells(t *testing.T) {
+	// two loose boxes and a tetrahedron touching the second at a corner
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 1, 1))
+	triangles = append(triangles, boxTriangles(fauxgl.V(3, 0, 0), fauxgl.V(5, 2, 2))...)
+	for _, tri := range tetrahedron() {
+		offset := fauxgl.V(5, 2, 2)
+		triangles = append(triangles, fauxgl.NewTriangleForPoints(
+			tri.V1.Position.Add(offset), tri.V2.Position.Add(offset), tri.V3.Position.Add(offset)))
+	}
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+
+	shells := FindShells(data)
+	if len(shells) != 2 {
+		t.Fatalf("%d shells, want 2", len(shells))
+	}
+	if len(shells[0].Triangles) != 12 || !nearly(shells[0].Volume, 1) {
+		t.Errorf("first shell has %d triangles and volume %g, want 12 and 1", len(shells[0].Triangles), shells[0].Volume)
+	}
+	if len(shells[1].Triangles) != 16 || !nearly(shells[1].Volume, 8+1.0/6) {
+		t.Errorf("second shell has %d triangles and volume %g, want 16 and %g", len(shells[1].Triangles), shells[1].Volume, 8+1.0/6)
+	}
+	if shells[0].Box.Max != fauxgl.V(1, 1, 1) {
+		t.Errorf("first shell box %v", shells[0].Box)
+	}
+
+	buffer := ShellBuffer(data, shells)
+	if len(buffer) != len(data.Buffer) {
+		t.Errorf("shell buffer has %d floats, want %d", len(buffer), len(data.Buffer))
+	}
+}
@@ -0,0 +1,106 @@
+package meshview
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sort"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/fogleman/slicer"
+)
+
+// SliceResult is one finished layer from a background slice; Layer is in
+// the plane space of Plane, and Run is the model's SliceRun it belongs to
+type SliceResult struct {
+	Model    *Model
+	Plane    Plane
+	Run      int
+	Index    int
+	Layer    slicer.Layer
+	Contours []*Contour
+	Stats    LayerStats
+	Repair   LayerRepair
+}
+
+// sliceTriangle is a slicer triangle with its z extent
+type sliceTriangle struct {
+	*slicer.Triangle
+	MinZ, MaxZ float64
+}
+
+// SliceModel slices model.Data along model.Plane at the Z of each layer in
+// model.Slices using background workers, sending each layer on ch as it
+// completes. It returns immediately; workers stop as soon as ctx is
+// cancelled.
+func SliceModel(ctx context.Context, model *Model, ch chan<- SliceResult) {
+	plane := model.Plane
+	run := model.SliceRun
+	tolerance := model.Tolerance
+	levels := make([]float64, len(model.Slices))
+	for i, layer := range model.Slices {
+		levels[i] = layer.Z
+	}
+	go func() {
+		// copy triangles into plane space, sorted by min z so each layer
+		// only scans a prefix
+		basis := plane.Basis()
+		identity := basis == fauxgl.Identity()
+		triangles := make([]sliceTriangle, model.Data.Count())
+		for i := range triangles {
+			t := model.Data.Triangle(i)
+			if !identity {
+				t = planeTriangle(t, basis)
+			}
+			box := t.BoundingBox()
+			triangles[i] = sliceTriangle{slicer.NewTriangle(t), box.Min.Z, box.Max.Z}
+		}
+		sort.Slice(triangles, func(i, j int) bool {
+			return triangles[i].MinZ < triangles[j].MinZ
+		})
+
+		count := len(levels)
+		wn := runtime.NumCPU() - 1
+		if wn < 1 {
+			wn = 1
+		}
+		for wi := 0; wi < wn; wi++ {
+			go func(wi int) {
+				var active []*slicer.Triangle
+				for i := wi; i < count && ctx.Err() == nil; i += wn {
+					z := levels[i]
+					active = active[:0]
+					for _, t := range triangles {
+						if t.MinZ > z {
+							break
+						}
+						if t.MaxZ >= z {
+							active = append(active, t.Triangle)
+						}
+					}
+					paths, repair := RepairPaths(slicer.GetPaths(active, z), z, tolerance)
+					logRepair(z, repair)
+					layer := slicer.Layer{Z: z, Paths: paths}
+					contours := NestPaths(layer.Paths)
+					result := SliceResult{model, plane, run, i, layer, contours, NewLayerStats(contours), repair}
+					select {
+					case ch <- result:
+						wake()
+					case <-ctx.Done():
+						return
+					}
+				}
+			}(wi)
+		}
+	}()
+}
+
+// logRepair logs any repairs made to the layer at z and any gaps left
+func logRepair(z float64, r LayerRepair) {
+	if r.Snapped > 0 || r.Dropped > 0 || r.Joined > 0 {
+		log.Println("slice", z, "snapped", r.Snapped, "dropped", r.Dropped, "joined", r.Joined)
+	}
+	for _, gap := range r.Gaps {
+		log.Println("slice", z, "has unclosed path", gap.Start, gap.End)
+	}
+}
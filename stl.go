@@ -2,15 +2,18 @@ package meshview
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"io"
 	//"log"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+
 	"github.com/fogleman/fauxgl"
 )
 
@@ -38,17 +41,85 @@ func FauxMesh2MeshData(mesh *fauxgl.Mesh) *MeshData {
 	return &md
 }
 
+// FauxMesh converts MeshData to a fauxgl.Mesh
+func (data *MeshData) FauxMesh() *fauxgl.Mesh {
+	triangles := make([]*fauxgl.Triangle, data.Count())
+	for i := range triangles {
+		triangles[i] = data.Triangle(i)
+	}
+	return fauxgl.NewTriangleMesh(triangles)
+}
+
+// Count returns the number of triangles in the buffer
+func (data *MeshData) Count() int {
+	return len(data.Buffer) / 9
+}
 
+// Triangle makes a fauxgl.Triangle of triangle i of the buffer
+func (data *MeshData) Triangle(i int) *fauxgl.Triangle {
+	b := data.Buffer[i*9:]
+	return fauxgl.NewTriangleForPoints(
+		fauxgl.V(float64(b[0]), float64(b[1]), float64(b[2])),
+		fauxgl.V(float64(b[3]), float64(b[4]), float64(b[5])),
+		fauxgl.V(float64(b[6]), float64(b[7]), float64(b[8])))
+}
+
+// SaveSTL writes the mesh as a binary STL file
+func (data *MeshData) SaveSTL(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	count := len(data.Buffer) / 9
+	header := make([]byte, 84)
+	binary.LittleEndian.PutUint32(header[80:], uint32(count))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]byte, 50)
+	for i := 0; i < count; i++ {
+		b := data.Buffer[i*9 : i*9+9]
+		v1 := fauxgl.V(float64(b[0]), float64(b[1]), float64(b[2]))
+		v2 := fauxgl.V(float64(b[3]), float64(b[4]), float64(b[5]))
+		v3 := fauxgl.V(float64(b[6]), float64(b[7]), float64(b[8]))
+		n := v2.Sub(v1).Cross(v3.Sub(v1))
+		if l := n.Length(); l > 0 {
+			n = n.DivScalar(l)
+		}
+		putFloat(record[0:], float32(n.X))
+		putFloat(record[4:], float32(n.Y))
+		putFloat(record[8:], float32(n.Z))
+		for j, f := range b {
+			putFloat(record[12+j*4:], f)
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
 
-// LoadSTL loads an STL file (MGD version, loads via fauxgl.LoadSTL)
+// LoadSTL loads an STL file, read a batch at a time so binary triangles
+// are parsed without a copy of the whole file in memory
 func LoadSTL(path string) (*MeshData, error) {
-	mesh, err := fauxgl.LoadSTL(path)
-	if err != nil { 
+	data, err := streamSTL(context.Background(), path, func([]float32, float64) {})
+	if err != nil {
 		return &MeshData{}, err
 	}
-	return FauxMesh2MeshData(mesh), err
+	return data, nil
 }
 
+// stlbSize returns the triangle count in header if it is that of a binary
+// stl of size bytes
+func stlbSize(header []byte, size int64) (int, bool) {
+	if len(header) < 84 {
+		return 0, false
+	}
+	count := int(binary.LittleEndian.Uint32(header[80:]))
+	return count, int64(count)*50+84 == size
+}
 
 func xLoadSTL(path string) (*MeshData, error) {
 	// open file
@@ -87,7 +158,7 @@ func xLoadSTL(path string) (*MeshData, error) {
 	return loadSTLA(file)
 }
 
-func loadSTLA(file *os.File) (*MeshData, error) {
+func loadSTLA(file io.Reader) (*MeshData, error) {
 	var data []float32
 	var x1, y1, z1, x2, y2, z2, x3, y3, z3 float32
 	i := 0
@@ -121,6 +192,9 @@ func loadSTLA(file *os.File) (*MeshData, error) {
 		}
 		i++
 	}
+	if len(data) == 0 {
+		return &MeshData{data, fauxgl.Box{}, nil}, scanner.Err()
+	}
 	box := boxForData(data)
 	return &MeshData{data, box, nil}, scanner.Err()
 }
@@ -129,6 +203,10 @@ func makeFloat(b []byte) float32 {
 	return math.Float32frombits(binary.LittleEndian.Uint32(b))
 }
 
+func putFloat(b []byte, f float32) {
+	binary.LittleEndian.PutUint32(b, math.Float32bits(f))
+}
+
 func loadSTLB(file *os.File, count int) (*MeshData, error) {
 	buf := make([]byte, count*50)
 	_, err := io.ReadFull(file, buf)
@@ -137,6 +215,19 @@ func loadSTLB(file *os.File, count int) (*MeshData, error) {
 	}
 
 	data := make([]float32, count*9)
+	parseSTLB(buf, data)
+	//log.Println(data)
+	if count == 0 {
+		return &MeshData{data, fauxgl.Box{}, nil}, nil
+	}
+	box := boxForData(data)
+	return &MeshData{data, box, nil}, nil
+}
+
+// parseSTLB parses the binary stl triangle records in buf into data, in
+// parallel
+func parseSTLB(buf []byte, data []float32) {
+	count := len(data) / 9
 	wn := runtime.NumCPU() - 1
 	if wn < 1 {
 		wn = 1
@@ -171,7 +262,140 @@ func loadSTLB(file *os.File, count int) (*MeshData, error) {
 		}(wi)
 	}
 	wg.Wait()
-	//log.Println(data)
-	box := boxForData(data)
-	return &MeshData{data, box, nil}, nil
+}
+
+// StreamBatch is about how many bytes of a file StreamMesh reads between
+// calls to its callback
+var StreamBatch = 4 << 20
+
+// StreamMesh loads an stl or obj file like LoadMesh, calling fn with each
+// batch of triangles read, as a de-indexed buffer, and the fraction of the
+// file read so far. Obj files arrive in one batch. The load stops early
+// with the context's error if ctx is cancelled.
+func StreamMesh(ctx context.Context, path string, fn func(buffer []float32, progress float64)) (*MeshData, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".stl" {
+		data, err := LoadMesh(path)
+		if err == nil {
+			fn(data.Buffer, 1)
+		}
+		return data, err
+	}
+	return streamSTL(ctx, path, fn)
+}
+
+// streamSTL is StreamMesh for an stl file. The file is read rather than
+// mapped: one rewritten while it loads then fails to parse instead of
+// faulting.
+func streamSTL(ctx context.Context, path string, fn func([]float32, float64)) (*MeshData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 84)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if count, ok := stlbSize(header[:n], info.Size()); ok {
+		return streamSTLB(ctx, file, count, fn)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return streamSTLA(ctx, file, info.Size(), fn)
+}
+
+// streamSTLB reads and parses count binary stl triangle records from file
+// a batch at a time
+func streamSTLB(ctx context.Context, file io.Reader, count int, fn func([]float32, float64)) (*MeshData, error) {
+	data := make([]float32, count*9)
+	batch := StreamBatch / 50
+	if batch < 1 {
+		batch = 1
+	}
+	if batch > count {
+		batch = count
+	}
+	buf := make([]byte, batch*50)
+	for i0 := 0; i0 < count; i0 += batch {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		i1 := i0 + batch
+		if i1 > count {
+			i1 = count
+		}
+		b := buf[:(i1-i0)*50]
+		if _, err := io.ReadFull(file, b); err != nil {
+			return nil, err
+		}
+		parseSTLB(b, data[i0*9:i1*9])
+		fn(data[i0*9:i1*9], float64(i1)/float64(count))
+	}
+	if count == 0 {
+		return &MeshData{data, fauxgl.Box{}, nil}, nil
+	}
+	return &MeshData{data, boxForData(data), nil}, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// streamSTLA reads an ascii stl, passing on what it has parsed every
+// StreamBatch bytes
+func streamSTLA(ctx context.Context, file io.Reader, size int64, fn func([]float32, float64)) (*MeshData, error) {
+	var data []float32
+	var corners []float32
+	sent := 0
+	next := int64(StreamBatch)
+	reader := &countingReader{r: file}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 12 || line[0] != 'v' {
+			continue
+		}
+		fields := strings.Fields(line[7:])
+		if len(fields) != 3 {
+			continue
+		}
+		for _, f := range fields {
+			v, _ := strconv.ParseFloat(f, 32)
+			corners = append(corners, float32(v))
+		}
+		if len(corners) == 9 {
+			data = append(data, corners...)
+			corners = corners[:0]
+		}
+		if reader.n >= next {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			fn(data[sent:], float64(reader.n)/float64(size))
+			sent = len(data)
+			next = reader.n + int64(StreamBatch)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	fn(data[sent:], 1)
+	if len(data) == 0 {
+		return &MeshData{data, fauxgl.Box{}, nil}, nil
+	}
+	return &MeshData{data, boxForData(data), nil}, nil
 }
@@ -0,0 +1,120 @@
+package meshview
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// streamAll streams path in small batches, returning the loaded data, the
+// batches joined and the progress reported with each
+func streamAll(t *testing.T, path string, batch int) (*MeshData, []float32, []float64) {
+	defer func(b int) { StreamBatch = b }(StreamBatch)
+	StreamBatch = batch
+	var joined []float32
+	var progress []float64
+	data, err := StreamMesh(context.Background(), path, func(buffer []float32, p float64) {
+		joined = append(joined, buffer...)
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, joined, progress
+}
+
+func TestStreamMesh(t *testing.T) {
+	box := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(1, 2, 3))))
+	path := filepath.Join(t.TempDir(), "box.stl")
+	if err := box.SaveSTL(path); err != nil {
+		t.Fatal(err)
+	}
+	data, joined, progress := streamAll(t, path, 5*50)
+	if len(progress) != 3 || progress[2] != 1 {
+		t.Errorf("progress %v, want 3 batches ending at 1", progress)
+	}
+	if len(joined) != len(box.Buffer) || len(data.Buffer) != len(box.Buffer) {
+		t.Fatalf("streamed %d and loaded %d floats, want %d", len(joined), len(data.Buffer), len(box.Buffer))
+	}
+	for i := range joined {
+		if joined[i] != box.Buffer[i] || data.Buffer[i] != box.Buffer[i] {
+			t.Fatalf("float %d differs", i)
+		}
+	}
+	if data.Box != box.Box {
+		t.Errorf("box %v, want %v", data.Box, box.Box)
+	}
+
+	// the same as ascii
+	var b strings.Builder
+	b.WriteString("solid box\n")
+	for i := 0; i < len(box.Buffer); i += 9 {
+		b.WriteString("facet normal 0 0 0\nouter loop\n")
+		for j := i; j < i+9; j += 3 {
+			fmt.Fprintf(&b, "vertex %g %g %g\n", box.Buffer[j], box.Buffer[j+1], box.Buffer[j+2])
+		}
+		b.WriteString("endloop\nendfacet\n")
+	}
+	b.WriteString("endsolid box\n")
+	path = filepath.Join(t.TempDir(), "ascii.stl")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, joined, progress = streamAll(t, path, 256)
+	if len(progress) < 2 || progress[len(progress)-1] != 1 {
+		t.Errorf("progress %v, want several batches ending at 1", progress)
+	}
+	if len(joined) != len(box.Buffer) || len(data.Buffer) != len(box.Buffer) {
+		t.Fatalf("streamed %d and loaded %d floats, want %d", len(joined), len(data.Buffer), len(box.Buffer))
+	}
+}
+
+func TestLoadSTL(t *testing.T) {
+	box := FauxMesh2MeshData(fauxgl.NewTriangleMesh(boxTriangles(fauxgl.V(-1, 0, 0), fauxgl.V(1, 2, 5))))
+	path := filepath.Join(t.TempDir(), "box.stl")
+	if err := box.SaveSTL(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Buffer) != len(box.Buffer) || data.Box != box.Box {
+		t.Fatalf("loaded %d floats in %v, want %d in %v", len(data.Buffer), data.Box, len(box.Buffer), box.Box)
+	}
+	for i := range data.Buffer {
+		if data.Buffer[i] != box.Buffer[i] {
+			t.Fatalf("float %d is %g, want %g", i, data.Buffer[i], box.Buffer[i])
+		}
+	}
+}
+
+func TestLoadSTLEmpty(t *testing.T) {
+	// an empty file, an ascii solid with no facets and a binary one cut
+	// short all load as no triangles
+	dir := t.TempDir()
+	files := map[string]string{
+		"empty.stl": "",
+		"solid.stl": "solid empty\nendsolid empty\n",
+		"short.stl": string(make([]byte, 84)) + "\x00",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		data, err := LoadSTL(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(data.Buffer) != 0 || data.Box != (fauxgl.Box{}) {
+			t.Errorf("%s: loaded %d floats in %v, want none", name, len(data.Buffer), data.Box)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package meshview
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/fogleman/fauxgl"
+)
+
+// DefaultMinThickness is the thinnest wall, in model units, that is
+// expected to print
+var DefaultMinThickness = 0.8
+
+// Thickness returns the wall thickness at each triangle of data, measured
+// from its center along the inward normal to the first face hit. Triangles
+// with no face behind them, as on an open mesh, are +Inf.
+func Thickness(data *MeshData) []float64 {
+	triangles := BufferTriangles(data.Buffer)
+	bvh := NewBVH(triangles)
+
+	thickness := make([]float64, len(triangles))
+	count := len(triangles)
+	wn := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for wi := 0; wi < wn; wi++ {
+		wg.Add(1)
+		go func(wi int) {
+			for i := wi; i < count; i += wn {
+				t := triangles[i]
+				n := t[1].Sub(t[0]).Cross(t[2].Sub(t[0]))
+				if n.Length() == 0 {
+					thickness[i] = math.Inf(1)
+					continue
+				}
+				center := t[0].Add(t[1]).Add(t[2]).DivScalar(3)
+				_, thickness[i] = bvh.Raycast(center, n.Normalize().Negate(), i)
+			}
+			wg.Done()
+		}(wi)
+	}
+	wg.Wait()
+	return thickness
+}
+
+// ThinRegion is a connected patch of triangles thinner than the minimum
+type ThinRegion struct {
+	Triangles int
+	Area      float64
+	Thinnest  float64
+	Center    fauxgl.Vector // area weighted
+}
+
+// ThicknessReport summarizes the wall thickness of a mesh
+type ThicknessReport struct {
+	Minimum  float64
+	Thinnest float64
+	Area     float64 // of the triangles thinner than Minimum
+	Regions  []ThinRegion
+}
+
+// Thin reports whether any wall is thinner than the minimum
+func (r ThicknessReport) Thin() bool {
+	return len(r.Regions) > 0
+}
+
+// Print writes a readable summary of the report
+func (r ThicknessReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "minimum   %g\n", r.Minimum)
+	fmt.Fprintf(w, "thinnest  %g\n", r.Thinnest)
+	fmt.Fprintf(w, "thin area %g\n", r.Area)
+	fmt.Fprintf(w, "regions   %d\n", len(r.Regions))
+	for i, g := range r.Regions {
+		fmt.Fprintf(w, "%4d  thinnest %g area %g triangles %d at %g %g %g\n",
+			i+1, g.Thinnest, g.Area, g.Triangles, g.Center.X, g.Center.Y, g.Center.Z)
+	}
+}
+
+// SummarizeThickness groups the triangles of data thinner than minimum
+// into connected regions, thinnest first
+func SummarizeThickness(data *MeshData, thickness []float64, minimum float64) ThicknessReport {
+	r := ThicknessReport{Minimum: minimum, Thinnest: math.Inf(1)}
+	triangles := BufferTriangles(data.Buffer)
+	vertices, indices := Weld(data.Buffer, WeldTolerance(data.Box))
+
+	var thin []int
+	var thinIndices []uint32
+	for i, d := range thickness {
+		r.Thinnest = math.Min(r.Thinnest, d)
+		if d < minimum {
+			thin = append(thin, i)
+			thinIndices = append(thinIndices, indices[i*3:i*3+3]...)
+		}
+	}
+	labels := ComponentLabels(thinIndices, len(vertices))
+	r.Regions = make([]ThinRegion, countLabels(labels))
+	for j, i := range thin {
+		t := triangles[i]
+		a := t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Length() / 2
+		g := &r.Regions[labels[j]]
+		if g.Triangles == 0 {
+			g.Thinnest = thickness[i]
+		}
+		g.Triangles++
+		g.Area += a
+		g.Thinnest = math.Min(g.Thinnest, thickness[i])
+		g.Center = g.Center.Add(t[0].Add(t[1]).Add(t[2]).MulScalar(a / 3))
+		r.Area += a
+	}
+	for i := range r.Regions {
+		if g := &r.Regions[i]; g.Area > 0 {
+			g.Center = g.Center.DivScalar(g.Area)
+		}
+	}
+	sort.SliceStable(r.Regions, func(i, j int) bool {
+		return r.Regions[i].Thinnest < r.Regions[j].Thinnest
+	})
+	return r
+}
+
+// ThicknessFaceColors returns the heatmap color buffer for thickness: red
+// below minimum, fading through green to blue at three times minimum and
+// beyond
+func ThicknessFaceColors(thickness []float64, minimum float64) []float32 {
+	return FaceColors(len(thickness), func(i int) fauxgl.Vector {
+		return Ramp(1 - (thickness[i]-minimum)/(2*minimum))
+	})
+}
@@ -0,0 +1,48 @@
+package meshview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fogleman/fauxgl"
+)
+
+func TestThickness(t *testing.T) {
+	// a 4 x 4 slab 0.5 thick beside a 2 unit cube
+	triangles := boxTriangles(fauxgl.V(0, 0, 0), fauxgl.V(4, 4, 0.5))
+	triangles = append(triangles, boxTriangles(fauxgl.V(10, 0, 0), fauxgl.V(12, 2, 2))...)
+	data := FauxMesh2MeshData(fauxgl.NewTriangleMesh(triangles))
+
+	thickness := Thickness(data)
+	for i, want := range []float64{0.5, 0.5, 0.5, 0.5, 4, 4, 4, 4, 4, 4, 4, 4} {
+		if math.Abs(thickness[i]-want) > 1e-6 {
+			t.Errorf("slab face %d is %g thick, want %g", i, thickness[i], want)
+		}
+	}
+	for i, d := range thickness[12:] {
+		if math.Abs(d-2) > 1e-6 {
+			t.Errorf("cube face %d is %g thick, want 2", i, d)
+		}
+	}
+
+	// the slab's faces are thin, but only joined through its edges which
+	// are not, so each is a region
+	r := SummarizeThickness(data, thickness, 1)
+	if len(r.Regions) != 2 {
+		t.Fatalf("%d thin regions, want 2", len(r.Regions))
+	}
+	for _, g := range r.Regions {
+		if g.Triangles != 2 || math.Abs(g.Area-16) > 1e-6 || math.Abs(g.Thinnest-0.5) > 1e-6 {
+			t.Errorf("thin region %+v, want 2 triangles of area 16 and 0.5 thick", g)
+		}
+	}
+	if math.Abs(r.Area-32) > 1e-6 {
+		t.Errorf("thin area %g, want 32", r.Area)
+	}
+	if math.Abs(r.Thinnest-0.5) > 1e-6 {
+		t.Errorf("thinnest %g, want 0.5", r.Thinnest)
+	}
+	if r = SummarizeThickness(data, thickness, 0.25); r.Thin() {
+		t.Errorf("%d thin regions below 0.25", len(r.Regions))
+	}
+}
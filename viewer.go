@@ -0,0 +1,1189 @@
+package meshview
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fogleman/fauxgl"
+	"github.com/go-gl/gl/v2.1/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// ViewerOptions configure a new Viewer
+type ViewerOptions struct {
+	Title         string // shown until a model loads
+	Width, Height int    // of the window, maximized if zero
+	// Compare, when set, is a mesh each loaded model is aligned with per
+	// Alignment and measured against, with DeviationScale shaded fully red
+	// or blue, or the largest deviation if zero
+	Compare        string
+	Alignment      Alignment
+	DeviationScale float64
+	Shade          ShadeMode // how meshes are colored at first
+	Layout         Layout    // where the models of a scene are placed at first
+	// Viewports split the window, DefaultViewports if nil
+	Viewports []Viewport
+	// Watch reloads the files loaded when they change on disk
+	Watch bool
+	// LodTarget is the triangle count of the proxies drawn while the view
+	// moves, negative for none; Overhang and MinThickness set the overhang
+	// and wall thickness analyses; Cache is where loaded models are cached.
+	// Each is the package default if zero.
+	LodTarget    int
+	Overhang     OverhangOptions
+	MinThickness float64
+	Cache        *Cache
+}
+
+// Viewer is a window split into viewports showing a scene of models and the
+// current layer of the active one, which is also the one inspected. It redraws
+// only when invalidated and otherwise sleeps until an event arrives. Its
+// methods must be called from the main thread, except Invalidate.
+type Viewer struct {
+	// OnModel is called when a model joins the scene, whether loaded, set
+	// or repaired
+	OnModel func(model *Model)
+	// OnKey is called with each key event before the viewer handles it,
+	// which it skips if OnKey returns true
+	OnKey func(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) bool
+	// OnLayer is called when the current layer changes
+	OnLayer func(index int)
+	// OnDraw is called as each viewport is redrawn, after its models and
+	// with the gl viewport still set to it, with the matrix the active
+	// model, or the one the viewport shows, was drawn with there
+	OnDraw func(vp Viewport, matrix fauxgl.Matrix)
+
+	opts          ViewerOptions
+	window        *glfw.Window
+	interactor    Interactor
+	matrixUniform int32
+	colorAttrib   uint32
+	dirty         int32
+
+	// ctx aborts loading and the model's background tasks; background
+	// tasks send on these then wake the loop, so they are buffered to
+	// take a result while it sleeps
+	ctx         context.Context
+	cancel      context.CancelFunc
+	sliceCancel context.CancelFunc
+	ch          chan *Model
+	analysisCh  chan analysisResult
+	sliceCh     chan SliceResult
+	intersectCh chan intersectionResult
+	thicknessCh chan thicknessResult
+	proxyCh     chan proxyResult
+	batchCh     chan loadBatch
+	repairCh    chan repairResult
+	reloadCh    chan string
+
+	// models are the scene in load order and model is the active one,
+	// sliced and inspected; the rest are only drawn
+	models []*Model
+	active int
+	model  *Model
+	layout Layout
+	// loading previews each file still loading, by path, until its model
+	// arrives; replace clears the scene when the first of them does
+	loading map[string]*preview
+	replace bool
+	// reloading counts the reloads under way of each file that changed
+	reloading  map[string]int
+	sliceIndex int
+	// viewports split the window, one per model of the scene if split
+	viewports []Viewport
+	split     bool
+	// measuring holds the models whose thickness is being measured
+	measuring map[*Model]bool
+
+	// shadeMode is how the mesh is colored; the loop uploads the colors
+	// to the model when they differ
+	shadeMode         ShadeMode
+	showProblems      bool
+	showIntersections bool
+
+	// pendingNormal, when nonzero, asks the loop to reslice along a plane
+	// with this normal through the model's center
+	pendingNormal fauxgl.Vector
+	// pendingInfo prints the model's mass properties, pendingRepair
+	// repairs it and pendingExport saves the current layer
+	pendingInfo   bool
+	pendingRepair bool
+	pendingExport bool
+	// pendingIntersections toggles showing self-intersections, finding
+	// them if needed; intersectionStep moves to the next or previous pair
+	pendingIntersections bool
+	intersectionStep     int
+	// shellStep selects the next or previous shell; pendingHide hides or
+	// shows the selected shell and pendingIsolate shows it alone, or every
+	// shell if it already is; pendingShellExport saves it
+	shellStep          int
+	pendingHide        bool
+	pendingIsolate     bool
+	pendingShellExport bool
+
+	// the proxy is drawn until the view has been still for lodIdle
+	lastView   fauxgl.Matrix
+	lastMotion time.Time
+	proxyShown bool
+
+	lastTitle     string
+	memory        uint64
+	memorySampled time.Time
+}
+
+// openViewers counts the open viewers, glfw running while there are any
+var openViewers int
+
+// waking counts the viewers whose loops may be asleep waiting for events;
+// wakeMu is held while it is read for posting an event and while the last
+// viewer closes, so no event is posted once glfw is terminated
+var (
+	waking int
+	wakeMu sync.Mutex
+)
+
+// wake rouses the viewers' loop from waiting for events, after a
+// background task has sent it a result; it does nothing without a viewer
+func wake() {
+	wakeMu.Lock()
+	defer wakeMu.Unlock()
+	if waking != 0 {
+		glfw.PostEmptyEvent()
+	}
+}
+
+// NewViewer opens a window with no model in it
+func NewViewer(opts ViewerOptions) (*Viewer, error) {
+	if openViewers == 0 {
+		if err := glfw.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	// create the window
+	glfw.WindowHint(glfw.Samples, 4)
+	glfw.WindowHint(glfw.ContextVersionMajor, 2)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	width, height := opts.Width, opts.Height
+	if width == 0 || height == 0 {
+		width, height = 1920, 1080
+	}
+	window, err := glfw.CreateWindow(width, height, opts.Title, nil, nil)
+	if err != nil {
+		if openViewers == 0 {
+			glfw.Terminate()
+		}
+		return nil, err
+	}
+	openViewers++
+	if opts.Width == 0 || opts.Height == 0 {
+		window.Maximize()
+	}
+	window.MakeContextCurrent()
+
+	if opts.LodTarget == 0 {
+		opts.LodTarget = LodTarget
+	}
+	if opts.Overhang == (OverhangOptions{}) {
+		opts.Overhang = DefaultOverhangOptions
+	}
+	if opts.MinThickness == 0 {
+		opts.MinThickness = DefaultMinThickness
+	}
+	if opts.Cache == nil {
+		opts.Cache = ModelCache
+	}
+	v := &Viewer{opts: opts, window: window, dirty: 1}
+	v.ctx, v.cancel = context.WithCancel(context.Background())
+	v.sliceCancel = func() {}
+	v.ch = make(chan *Model, 1)
+	v.analysisCh = make(chan analysisResult, 1)
+	v.sliceCh = make(chan SliceResult, SliceCount)
+	v.intersectCh = make(chan intersectionResult, 1)
+	v.thicknessCh = make(chan thicknessResult, 1)
+	v.proxyCh = make(chan proxyResult, 1)
+	v.batchCh = make(chan loadBatch, 1)
+	v.repairCh = make(chan repairResult, 1)
+	v.reloadCh = make(chan string, 1)
+	v.loading = map[string]*preview{}
+	v.reloading = map[string]int{}
+	v.layout = opts.Layout
+	v.viewports = opts.Viewports
+	if v.viewports == nil {
+		v.viewports = DefaultViewports()
+	}
+	v.measuring = map[*Model]bool{}
+	v.shadeMode = opts.Shade
+	v.showProblems = true
+	wakeMu.Lock()
+	waking++
+	wakeMu.Unlock()
+
+	// initialize gl
+	if err := gl.Init(); err != nil {
+		v.Close()
+		return nil, err
+	}
+
+	// MGD
+	//gl.Enable(gl.BLEND)
+	//gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+
+	glfw.SwapInterval(1)
+
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.CULL_FACE)
+	gl.CullFace(gl.BACK)
+	gl.ClearColor(float32(0xd4)/255, float32(0xd9)/255, float32(0xde)/255, 1)
+
+	// compile shaders
+	program, err := compileProgram(vertexShader, fragmentShader)
+	if err != nil {
+		v.Close()
+		return nil, err
+	}
+	gl.UseProgram(program)
+
+	v.matrixUniform = uniformLocation(program, "matrix")
+	v.colorAttrib = attribLocation(program, "color")
+
+	// Get supported line width range and step size
+	var lineWidthSizes [2]float32
+	gl.GetFloatv(gl.LINE_WIDTH_RANGE, &lineWidthSizes[0])
+	var lineWidthStep float32
+	gl.GetFloatv(gl.LINE_WIDTH_GRANULARITY, &lineWidthStep)
+	log.Println("lws", lineWidthSizes, "lwstep", lineWidthStep)
+	gl.LineWidth(1)
+	gl.Disable(gl.LINE_STIPPLE)
+	gl.Enable(gl.LINE_SMOOTH)
+	gl.Hint(gl.LINE_SMOOTH_HINT, gl.NICEST)
+
+	// create interactor
+	v.interactor = NewArcball()
+	v.bind(v.interactor)
+
+	// render during resize, and whenever the window is uncovered
+	window.SetFramebufferSizeCallback(func(window *glfw.Window, w, h int) {
+		window.MakeContextCurrent()
+		gl.Viewport(0, 0, int32(w), int32(h))
+		v.Invalidate()
+		v.render()
+	})
+	window.SetRefreshCallback(func(window *glfw.Window) {
+		v.Invalidate()
+		v.render()
+	})
+
+	// handle drop events
+	window.SetDropCallback(func(window *glfw.Window, filenames []string) {
+		v.Load(filenames...)
+	})
+	return v, nil
+}
+
+// Window returns the viewer's window
+func (v *Viewer) Window() *glfw.Window {
+	return v.window
+}
+
+// Model returns the active model, nil until one has loaded
+func (v *Viewer) Model() *Model {
+	return v.model
+}
+
+// Models returns the models of the scene in the order they arrived
+func (v *Viewer) Models() []*Model {
+	return v.models
+}
+
+// Active returns the index of the active model
+func (v *Viewer) Active() int {
+	return v.active
+}
+
+// Load loads the meshes at paths in parallel in the background, previewing
+// them as they arrive, and shows them in place of the current scene
+func (v *Viewer) Load(paths ...string) {
+	v.cancel()
+	v.ctx, v.cancel = context.WithCancel(context.Background())
+	v.clearLoading()
+	v.replace = true
+	v.Add(paths...)
+	v.window.SetTitle(strings.Join(paths, " "))
+}
+
+// Add loads the meshes at paths in parallel in the background, adding them
+// to the scene as they arrive, and watches them if the viewer watches.
+// Models are told apart by path, so a path already loading or in the scene
+// is skipped.
+func (v *Viewer) Add(paths ...string) {
+	var added []string
+	for _, path := range paths {
+		if v.has(path) {
+			log.Println("skipping", path, "as it is already in the scene")
+			continue
+		}
+		v.loading[path] = &preview{Path: path}
+		v.loadModel(v.ctx, path)
+		added = append(added, path)
+	}
+	if v.opts.Watch && len(added) > 0 {
+		v.watch(added)
+	}
+}
+
+// has reports whether the model at path is loading or, unless the scene is
+// being replaced, in the scene
+func (v *Viewer) has(path string) bool {
+	if _, ok := v.loading[path]; ok {
+		return true
+	}
+	if v.replace {
+		return false
+	}
+	for _, model := range v.models {
+		if model.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// watch has the loop reload any of paths that changes, until the scene is
+// replaced
+func (v *Viewer) watch(paths []string) {
+	ctx, ch := v.ctx, v.reloadCh
+	go func() {
+		err := WatchFiles(ctx, paths, func(path string) {
+			select {
+			case ch <- path:
+				wake()
+			case <-ctx.Done():
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Println("watch error", err)
+		}
+	}()
+}
+
+// reload loads the file at path again, to take the place of its model
+func (v *Viewer) reload(path string) {
+	for _, model := range v.models {
+		if model.Path == path {
+			log.Println("reloading", path)
+			v.reloading[path]++
+			v.loadModel(v.ctx, path)
+			return
+		}
+	}
+}
+
+// reloaded counts off a reload of the file at path, reporting whether one
+// was under way
+func (v *Viewer) reloaded(path string) bool {
+	n := v.reloading[path]
+	if n == 0 {
+		return false
+	}
+	if n == 1 {
+		delete(v.reloading, path)
+	} else {
+		v.reloading[path] = n - 1
+	}
+	return true
+}
+
+// clearLoading forgets the files loading, freeing their previews
+func (v *Viewer) clearLoading() {
+	v.window.MakeContextCurrent()
+	for path, p := range v.loading {
+		p.Destroy()
+		delete(v.loading, path)
+	}
+	v.reloading = map[string]int{}
+	v.replace = false
+}
+
+// SetModel shows model in place of the current scene, cancelling any load.
+// A model not yet analyzed is analyzed in the background after.
+func (v *Viewer) SetModel(model *Model) {
+	v.cancel()
+	v.ctx, v.cancel = context.WithCancel(context.Background())
+	v.clearLoading()
+	v.replace = true
+	v.AddModel(model)
+}
+
+// AddModel adds model to the scene, analyzing it in the background after
+// if it has not been
+func (v *Viewer) AddModel(model *Model) {
+	v.add(model)
+	if model.Indexed == nil {
+		findAnalysis(v.ctx, model, v.opts.Overhang, v.analysisCh)
+	}
+}
+
+// SetActive makes model i of the scene the one sliced and inspected
+func (v *Viewer) SetActive(i int) {
+	if i < 0 || i >= len(v.models) {
+		return
+	}
+	v.active = i
+	v.model = v.models[i]
+	v.Invalidate()
+	model := v.model
+	if model.SliceVaos == nil || model.Sliced < len(model.Slices) {
+		v.reslice(model.Plane)
+		return
+	}
+	// its layers are still uploaded from when it was last active
+	v.sliceCancel()
+	v.sliceIndex = nearestLayer(model, model.Plane)
+	if v.OnLayer != nil && len(model.Slices) > 0 {
+		v.OnLayer(v.sliceIndex)
+	}
+}
+
+// Viewports returns how the window is split
+func (v *Viewer) Viewports() []Viewport {
+	return v.viewports
+}
+
+// SetViewports splits the window into viewports
+func (v *Viewer) SetViewports(viewports []Viewport) {
+	v.viewports = viewports
+	v.split = false
+	v.Invalidate()
+}
+
+// SetLayout places the models of the scene per layout
+func (v *Viewer) SetLayout(layout Layout) {
+	v.layout = layout
+	ArrangeModels(v.models, layout)
+	v.Invalidate()
+}
+
+// Layer returns the index of the current layer
+func (v *Viewer) Layer() int {
+	return v.sliceIndex
+}
+
+// SetLayer makes layer i of the model current
+func (v *Viewer) SetLayer(i int) {
+	if v.model == nil || i < 0 || i >= len(v.model.Slices) || i == v.sliceIndex {
+		return
+	}
+	v.sliceIndex = i
+	v.Invalidate()
+	if v.OnLayer != nil {
+		v.OnLayer(i)
+	}
+}
+
+// Invalidate marks the view as needing a redraw and wakes the loop to draw
+// it. It may be called from any goroutine.
+func (v *Viewer) Invalidate() {
+	atomic.StoreInt32(&v.dirty, 1)
+	wake()
+}
+
+// redraw reports whether the view was invalidated, clearing it
+func (v *Viewer) redraw() bool {
+	return atomic.SwapInt32(&v.dirty, 0) != 0
+}
+
+// Run shows the viewer until its window is closed, then closes it
+func (v *Viewer) Run() {
+	RunViewers(v)
+}
+
+// RunViewers shows viewers until all their windows are closed, closing
+// each as its window is
+func RunViewers(viewers ...*Viewer) {
+	for {
+		timeout := memoryInterval
+		open := 0
+		for _, v := range viewers {
+			if v.window == nil {
+				continue
+			}
+			if !v.Update() {
+				v.Close()
+				continue
+			}
+			open++
+			if t := v.timeout(); t < timeout {
+				timeout = t
+			}
+		}
+		if open == 0 {
+			return
+		}
+		// sleep until an event or a background result
+		if timeout > 0 {
+			glfw.WaitEventsTimeout(timeout.Seconds())
+		} else {
+			glfw.PollEvents()
+		}
+	}
+}
+
+// timeout returns how long the loop may sleep: not at all while chunks
+// upload, and only until the proxy should give way to the mesh
+func (v *Viewer) timeout() time.Duration {
+	timeout := memoryInterval
+	for _, model := range v.models {
+		if model.UploadProgress() < 1 {
+			timeout = 0
+		}
+	}
+	if v.proxyShown {
+		if idle := lodIdle - time.Since(v.lastMotion); idle < timeout {
+			timeout = idle
+		}
+	}
+	return timeout
+}
+
+// Close stops loading and slicing, frees the models and destroys the window
+func (v *Viewer) Close() {
+	if v.window == nil {
+		return
+	}
+	v.cancel()
+	v.sliceCancel()
+	v.clearLoading()
+	for _, model := range v.models {
+		model.Destroy()
+	}
+	v.models, v.model = nil, nil
+	v.window.Destroy()
+	v.window = nil
+	wakeMu.Lock()
+	defer wakeMu.Unlock()
+	waking--
+	openViewers--
+	if openViewers == 0 {
+		glfw.Terminate()
+	}
+}
+
+// bind binds interactor to the window's callbacks, invalidating the view
+// after any event that may change it
+func (v *Viewer) bind(interactor Interactor) {
+	v.window.SetCursorPosCallback(func(window *glfw.Window, x, y float64) {
+		before := interactor.Matrix(window)
+		interactor.CursorPositionCallback(window, x, y)
+		if interactor.Matrix(window) != before {
+			v.Invalidate()
+		}
+	})
+	v.window.SetMouseButtonCallback(func(window *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		interactor.MouseButtonCallback(window, button, action, mods)
+		v.Invalidate()
+	})
+	v.window.SetKeyCallback(func(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if v.OnKey != nil && v.OnKey(key, action, mods) {
+			return
+		}
+		interactor.KeyCallback(window, key, scancode, action, mods)
+		v.keyCallback(key, action, mods)
+		v.Invalidate()
+	})
+	v.window.SetScrollCallback(func(window *glfw.Window, dx, dy float64) {
+		interactor.ScrollCallback(window, dx, dy)
+		v.Invalidate()
+	})
+}
+
+// keyCallback handles the keys for slicing, shading and inspecting the
+// active model and for arranging the scene; the interactor handles those moving the camera
+func (v *Viewer) keyCallback(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+	if (action != glfw.Press && action != glfw.Repeat) || mods != 0 {
+		return
+	}
+	// toggle switches the shading between mode and solid
+	toggle := func(mode ShadeMode) {
+		if v.shadeMode == mode {
+			v.shadeMode = ShadeSolid
+		} else {
+			v.shadeMode = mode
+		}
+	}
+	switch key {
+	case glfw.KeyUp:
+		v.SetLayer(v.sliceIndex + 1)
+	case glfw.KeyDown:
+		v.SetLayer(v.sliceIndex - 1)
+	case glfw.KeyX:
+		v.pendingNormal = PlaneX.Normal
+	case glfw.KeyY:
+		v.pendingNormal = PlaneY.Normal
+	case glfw.KeyZ:
+		v.pendingNormal = PlaneZ.Normal
+	case glfw.KeyC:
+		v.showProblems = !v.showProblems
+	case glfw.KeyI:
+		v.pendingInfo = true
+	case glfw.KeyR:
+		v.pendingRepair = true
+	case glfw.KeyS:
+		v.pendingIntersections = true
+	case glfw.KeyN:
+		v.intersectionStep = 1
+	case glfw.KeyB:
+		v.intersectionStep = -1
+	case glfw.KeyE:
+		v.pendingExport = true
+	case glfw.KeyO:
+		toggle(ShadeOverhang)
+	case glfw.KeyD:
+		toggle(ShadeDeviation)
+	case glfw.KeyG:
+		toggle(ShadeShells)
+	case glfw.KeyT:
+		toggle(ShadeThickness)
+	case glfw.KeyRightBracket:
+		v.shellStep = 1
+	case glfw.KeyLeftBracket:
+		v.shellStep = -1
+	case glfw.KeyH:
+		v.pendingHide = true
+	case glfw.KeyL:
+		v.pendingIsolate = true
+	case glfw.KeyW:
+		v.pendingShellExport = true
+	case glfw.KeyTab:
+		if len(v.models) > 0 {
+			v.SetActive((v.active + 1) % len(v.models))
+		}
+	case glfw.KeyV:
+		if v.model != nil {
+			v.model.Visible = !v.model.Visible
+		}
+	case glfw.KeyM:
+		if v.split {
+			v.SetViewports(DefaultViewports())
+		} else {
+			v.SetViewports(SplitViewports(len(v.models)))
+			v.split = true
+		}
+	case glfw.KeyA:
+		if v.layout == LayoutSideBySide {
+			v.SetLayout(LayoutOriginal)
+		} else {
+			v.SetLayout(LayoutSideBySide)
+		}
+	case glfw.KeyP:
+		// slice perpendicular to the current view direction
+		if a, ok := v.interactor.(*Arcball); ok {
+			v.pendingNormal = a.Rotation.Transpose().MulDirection(fauxgl.V(0, 1, 0))
+		}
+	}
+}
+
+// add adds model to the scene, first clearing the scene if it is the first
+// of a load replacing it, and makes it active if it is alone
+func (v *Viewer) add(model *Model) {
+	v.window.MakeContextCurrent()
+	if p := v.loading[model.Path]; p != nil {
+		// an aligned mesh has moved away from its preview
+		if model.Preview == nil && model.ComparePath == "" {
+			model.Preview, p.Vaos = p.Vaos, nil
+		}
+		p.Destroy()
+		delete(v.loading, model.Path)
+	}
+	if v.replace {
+		for _, m := range v.models {
+			m.Destroy()
+		}
+		v.models, v.model, v.active = nil, nil, 0
+		v.measuring = map[*Model]bool{}
+		v.replace = false
+	}
+	v.models = append(v.models, model)
+	ArrangeModels(v.models, v.layout)
+	if v.split {
+		v.viewports = SplitViewports(len(v.models))
+	}
+	v.proxy(model)
+	if v.model == nil {
+		v.SetActive(0)
+	}
+	v.Invalidate()
+	if v.OnModel != nil {
+		v.OnModel(model)
+	}
+}
+
+// replaceModel puts model in the place of old in the scene, keeping its
+// look and slicing, and frees old. The scene is arranged again around the
+// new mesh while the camera stays put, so the view does not jump.
+func (v *Viewer) replaceModel(old, model *Model) {
+	i := v.index(old)
+	if i < 0 {
+		return
+	}
+	v.window.MakeContextCurrent()
+	model.Color, model.Visible = old.Color, old.Visible
+	model.LayerHeight = old.LayerHeight
+	if model.Plane != old.Plane {
+		model.SetPlane(old.Plane)
+	}
+	// the old mesh stands in until the new one is uploaded
+	if model.Preview == nil && model.Indexed == nil {
+		model.Preview, old.Preview = old.Preview, nil
+		if model.Preview == nil {
+			model.Preview, old.ChunkVaos = old.ChunkVaos, nil
+		}
+	}
+	old.Destroy()
+	v.models[i] = model
+	ArrangeModels(v.models, v.layout)
+	v.proxy(model)
+	if i == v.active {
+		index := v.sliceIndex
+		v.model = nil
+		v.SetActive(i)
+		if index >= len(model.Slices) {
+			index = len(model.Slices) - 1
+		}
+		v.SetLayer(index)
+		if v.showIntersections {
+			findIntersections(v.ctx, model, v.intersectCh)
+		}
+	}
+	v.Invalidate()
+	if v.OnModel != nil {
+		v.OnModel(model)
+	}
+}
+
+// proxy builds the model's proxy in the background once it is indexed, if
+// it is large enough to need one
+func (v *Viewer) proxy(model *Model) {
+	if lod := v.opts.LodTarget; lod > 0 && model.Indexed != nil && model.Data.Count() > lod {
+		buildProxy(v.ctx, model, lod, v.proxyCh)
+	}
+}
+
+// index returns where model is in the scene, or -1 if it is not
+func (v *Viewer) index(model *Model) int {
+	for i, m := range v.models {
+		if m == model {
+			return i
+		}
+	}
+	return -1
+}
+
+// color returns the plain color of model i: its own if set, otherwise
+// meshColor when alone and a palette color in a scene of several
+func (v *Viewer) color(i int) fauxgl.Vector {
+	if c := v.models[i].Color; c != (fauxgl.Vector{}) {
+		return c
+	}
+	if len(v.models) == 1 {
+		return meshColor
+	}
+	return Palette(i)
+}
+
+// reslice cancels any slicing in progress and restarts it along plane
+func (v *Viewer) reslice(plane Plane) {
+	model := v.model
+	v.sliceCancel()
+	var sliceCtx context.Context
+	sliceCtx, v.sliceCancel = context.WithCancel(v.ctx)
+	model.SetPlane(plane)
+	model.DeleteSliceVaos()
+	model.SliceVaos = make([][]Vao, len(model.Slices))
+	index := nearestLayer(model, plane)
+	v.sliceIndex = index
+	if v.OnLayer != nil && len(model.Slices) > 0 {
+		v.OnLayer(index)
+	}
+	v.Invalidate()
+	if model.Sliced == len(model.Slices) {
+		// the layers came from the cache
+		for i, layer := range model.Slices {
+			for _, p := range layer.Paths {
+				model.SliceVaos[i] = append(model.SliceVaos[i], Vectors2Vao(p))
+			}
+		}
+		return
+	}
+	SliceModel(sliceCtx, model, v.sliceCh)
+}
+
+// nearestLayer returns the index of the model's layer closest to plane
+func nearestLayer(model *Model, plane Plane) int {
+	index := 0
+	for i, layer := range model.Slices {
+		if math.Abs(layer.Z-plane.Offset()) < math.Abs(model.Slices[index].Z-plane.Offset()) {
+			index = i
+		}
+	}
+	return index
+}
+
+// Update handles what has happened since it was last called, from events
+// to background results, and redraws if need be without waiting. It
+// returns false once the window has been asked to close.
+func (v *Viewer) Update() bool {
+	if v.window == nil || v.window.ShouldClose() {
+		return false
+	}
+	v.window.MakeContextCurrent()
+	select {
+	case model := <-v.ch:
+		if v.reloaded(model.Path) {
+			for _, old := range v.models {
+				if old.Path == model.Path {
+					v.replaceModel(old, model)
+					break
+				}
+			}
+		} else if _, ok := v.loading[model.Path]; ok {
+			v.add(model)
+		}
+	case r := <-v.analysisCh:
+		if v.index(r.Model) >= 0 {
+			v.window.MakeContextCurrent()
+			r.apply()
+			v.proxy(r.Model)
+			// colors asked for before the analyses came are uploaded now
+			updateShade(r.Model, r.Model.Shade)
+			v.Invalidate()
+		}
+	case r := <-v.repairCh:
+		v.replaceModel(r.Model, r.Repaired)
+	case path := <-v.reloadCh:
+		v.reload(path)
+	default:
+	}
+	// show the triangles of loading files as they arrive
+	for done := false; !done; {
+		select {
+		case b := <-v.batchCh:
+			p := v.loading[b.Path]
+			if p == nil {
+				if b.Err != nil {
+					v.reloaded(b.Path)
+				}
+				break
+			}
+			if b.Err != nil {
+				p.Destroy()
+				delete(v.loading, b.Path)
+				v.replace = v.replace && len(v.loading) > 0
+			} else {
+				p.Add(b)
+			}
+			v.Invalidate()
+		default:
+			done = true
+		}
+	}
+	for _, model := range v.models {
+		if model.UploadChunks(chunkBudget) {
+			v.Invalidate()
+			break
+		}
+	}
+	model := v.model
+	if model != nil && v.pendingNormal != (fauxgl.Vector{}) {
+		v.reslice(NewPlane(model.Data.Box.Center(), v.pendingNormal))
+	}
+	v.pendingNormal = fauxgl.Vector{}
+	if model != nil && v.pendingExport && v.sliceIndex < len(model.Slices) {
+		exportLayer(model, v.sliceIndex)
+	}
+	v.pendingExport = false
+	if model != nil && model.Shade != v.shadeMode {
+		if v.shadeMode == ShadeThickness {
+			v.measure(model)
+		}
+		updateShade(model, v.shadeMode)
+		v.Invalidate()
+	}
+	for _, vp := range v.viewports {
+		for i, m := range v.models {
+			if vp.Shade == ShadeThickness && !vp.Layer && (vp.Model < 0 || vp.Model == i) {
+				v.measure(m)
+			}
+		}
+	}
+	if model != nil && model.Shade == ShadeShells && len(model.Shells) > 0 {
+		n := len(model.Shells)
+		model.Shell = (model.Shell + v.shellStep + n) % n
+		if v.pendingHide {
+			model.Hidden[model.Shell] = !model.Hidden[model.Shell]
+		}
+		if v.pendingIsolate {
+			isolated := !model.Hidden[model.Shell]
+			for i := range model.Hidden {
+				isolated = isolated && (i == model.Shell || model.Hidden[i])
+			}
+			for i := range model.Hidden {
+				model.Hidden[i] = !isolated && i != model.Shell
+			}
+		}
+		if v.pendingShellExport {
+			exportShell(model, model.Shell)
+		}
+		if v.shellStep != 0 || v.pendingHide || v.pendingIsolate {
+			v.Invalidate()
+		}
+	}
+	v.shellStep = 0
+	v.pendingHide, v.pendingIsolate, v.pendingShellExport = false, false, false
+	if model != nil && v.pendingInfo {
+		model.Mass.Print(os.Stdout)
+	}
+	v.pendingInfo = false
+	if model != nil && v.pendingRepair {
+		repairModel(v.ctx, model, v.opts.Overhang, v.repairCh)
+	}
+	v.pendingRepair = false
+	if model != nil && v.pendingIntersections {
+		v.showIntersections = !v.showIntersections
+		if v.showIntersections && model.Intersections == nil {
+			findIntersections(v.ctx, model, v.intersectCh)
+		}
+		v.Invalidate()
+	}
+	v.pendingIntersections = false
+	if model != nil && v.showIntersections && v.intersectionStep != 0 && len(model.Intersections) > 0 {
+		n := len(model.Intersections)
+		model.Intersection = (model.Intersection + v.intersectionStep + n) % n
+		frameIntersection(v.interactor, model)
+	}
+	v.intersectionStep = 0
+	select {
+	case r := <-v.intersectCh:
+		if r.Model == model {
+			model.Intersections = r.Pairs
+			model.Intersection = 0
+			frameIntersection(v.interactor, model)
+			v.Invalidate()
+		}
+	case r := <-v.proxyCh:
+		if v.index(r.Model) >= 0 {
+			r.Model.ProxyVao.Delete()
+			r.Model.ProxyVao = NewVao(r.Buffer)
+			r.Model.ProxyFaces = r.Faces
+		}
+	case r := <-v.thicknessCh:
+		delete(v.measuring, r.Model)
+		if v.index(r.Model) >= 0 {
+			r.Model.Thickness = r.Thickness
+			r.Model.Thin = r.Report
+			if r.Model == model {
+				updateShade(model, v.shadeMode)
+			}
+			v.Invalidate()
+		}
+	default:
+	}
+	// collect any finished layers
+	for done := false; !done; {
+		select {
+		case r := <-v.sliceCh:
+			if r.Model != model || r.Run != model.SliceRun {
+				break
+			}
+			model.AddSlice(r)
+			vaos := []Vao{}
+			for _, p := range r.Layer.Paths {
+				vaos = append(vaos, Vectors2Vao(p))
+			}
+			model.SliceVaos[r.Index] = vaos
+			v.Invalidate()
+		default:
+			done = true
+		}
+	}
+	if model != nil {
+		if c := model.UncachedSlices(); c != nil {
+			model.Cached = c
+			saveCache(v.opts.Cache, model.CacheKey, model.Data, c)
+		}
+	}
+	if time.Since(v.memorySampled) >= memoryInterval {
+		v.memory = memoryUsage()
+		v.memorySampled = time.Now()
+	}
+	title := ""
+	if v.previewing() {
+		title = v.loadingTitle()
+	} else if model != nil {
+		title = v.modelTitle(model)
+		if n := len(v.loading); n > 0 {
+			title += fmt.Sprintf(" - loading %d more", n)
+		}
+	}
+	if title != "" {
+		title += " - memory " + formatBytes(v.memory)
+		if title != v.lastTitle {
+			v.window.SetTitle(title)
+			v.lastTitle = title
+		}
+	}
+	v.render()
+	return true
+}
+
+// measure measures the model's wall thickness in the background, unless
+// it has been or is being
+func (v *Viewer) measure(model *Model) {
+	if model.Thickness != nil || v.measuring[model] {
+		return
+	}
+	v.measuring[model] = true
+	findThickness(v.ctx, model, v.opts.MinThickness, v.thicknessCh)
+}
+
+// previewing reports whether the files loading are drawn in place of the
+// scene, which is until the first of them arrives
+func (v *Viewer) previewing() bool {
+	return len(v.loading) > 0 && (v.replace || len(v.models) == 0)
+}
+
+// render draws the loading previews or each viewport, if the view has been
+// invalidated
+func (v *Viewer) render() {
+	window := v.window
+	matrixUniform, colorAttrib := v.matrixUniform, v.colorAttrib
+	window.MakeContextCurrent()
+	// WAS gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
+	if v.previewing() {
+		if !v.redraw() {
+			return
+		}
+		gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+		box, ok := fauxgl.Box{}, false
+		for _, p := range v.loading {
+			if len(p.Vaos) == 0 {
+				continue
+			}
+			if ok {
+				box = box.Extend(p.Box)
+			} else {
+				box, ok = p.Box, true
+			}
+		}
+		if ok {
+			matrix := v.interactor.Matrix(window).Mul(BoxTransform(box))
+			setMatrix(matrixUniform, matrix.Translate(fauxgl.V(-0.5, 0, 0)))
+			setColor(colorAttrib, meshColor)
+			for _, p := range v.loading {
+				for _, vao := range p.Vaos {
+					vao.Draw()
+				}
+			}
+		}
+		drawProgress(matrixUniform, v.loadingProgress())
+		window.SwapBuffers()
+	} else if model := v.model; model != nil {
+		if view := v.interactor.Matrix(window); view != v.lastView {
+			v.lastView = view
+			v.lastMotion = time.Now()
+		}
+		hasProxy := false
+		for _, m := range v.models {
+			hasProxy = hasProxy || m.ProxyVao.Len != 0
+		}
+		if proxy := hasProxy && time.Since(v.lastMotion) < lodIdle; proxy != v.proxyShown {
+			v.proxyShown = proxy
+			v.Invalidate()
+		}
+		if v.redraw() {
+			gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+			w, h := window.GetFramebufferSize()
+			for _, vp := range v.viewports {
+				x, y, width, height := vp.Rect(w, h)
+				gl.Viewport(x, y, width, height)
+				view := project(window, v.interactor, width, height)
+				if vp.Layer {
+					v.renderLayer(view, model)
+				} else {
+					v.renderModels(view, vp)
+				}
+				if v.OnDraw != nil {
+					m := model
+					if !vp.Layer && vp.Model >= 0 && vp.Model < len(v.models) {
+						m = v.models[vp.Model]
+					}
+					v.OnDraw(vp, modelMatrix(view, vp, m))
+				}
+			}
+			gl.Viewport(0, 0, int32(w), int32(h))
+			window.SwapBuffers()
+		}
+	}
+}
+
+// modelMatrix returns the matrix model m is drawn with in vp, seen through
+// view: in its place in the scene, or fitted to vp if it shows only m
+func modelMatrix(view fauxgl.Matrix, vp Viewport, m *Model) fauxgl.Matrix {
+	if vp.Model >= 0 && !vp.Layer {
+		return view.Mul(BoxTransform(modelBox(m)))
+	}
+	return view.Mul(m.Transform)
+}
+
+// renderModels draws the models of viewport vp, seen through view, with
+// the problems of the active model
+func (v *Viewer) renderModels(view fauxgl.Matrix, vp Viewport) {
+	matrixUniform, colorAttrib := v.matrixUniform, v.colorAttrib
+	legend := false
+	for i, m := range v.models {
+		if vp.Model >= 0 && i != vp.Model || vp.Model < 0 && !m.Visible {
+			continue
+		}
+		matrix := modelMatrix(view, vp, m)
+		mode := vp.Shade
+		if mode == ShadeAuto {
+			mode = m.Shade
+		}
+		setMatrix(matrixUniform, matrix)
+		drawModel(colorAttrib, m, mode, v.color(i), v.proxyShown && m.ProxyVao.Len != 0, matrix)
+		if m == v.model && v.showProblems && m.Analysis != nil {
+			drawProblems(colorAttrib, m.Analysis)
+		}
+		if m == v.model && v.showIntersections {
+			drawIntersections(colorAttrib, m)
+		}
+		legend = legend || mode == ShadeDeviation && m.Deviation != nil
+	}
+	if legend {
+		drawLegend(matrixUniform, colorAttrib)
+	}
+}
+
+// renderLayer draws the current layer of model, seen through view, with
+// the slider and the slicing progress
+func (v *Viewer) renderLayer(view fauxgl.Matrix, model *Model) {
+	matrixUniform, colorAttrib := v.matrixUniform, v.colorAttrib
+	// slices are in plane space, so rotate them back into place
+	setMatrix(matrixUniform, view.Mul(model.Transform).Mul(model.Plane.Inverse()))
+	if v.sliceIndex < len(model.Slices) {
+		i := v.sliceIndex
+		drawLayer(colorAttrib, model.Slices[i], model.Contours[i], model.Repairs[i])
+	}
+	drawSlider(matrixUniform, colorAttrib, model, v.sliceIndex)
+	if model.Sliced < len(model.Slices) {
+		setColor(colorAttrib, meshColor)
+		drawProgress(matrixUniform, model.SliceProgress())
+	}
+}
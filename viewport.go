@@ -0,0 +1,85 @@
+package meshview
+
+import (
+	"github.com/fogleman/fauxgl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Viewport is a region of the viewer's window and what is drawn in it.
+// Every viewport is seen through the viewer's one camera, so moving the
+// view in any of them moves it in all.
+type Viewport struct {
+	// X, Y, Width and Height are fractions of the window, from its bottom
+	// left corner
+	X, Y, Width, Height float64
+	// Model is the index in the scene of the model shown, fitted to the
+	// viewport, or -1 for the whole scene
+	Model int
+	// Shade is how the models are colored; ShadeAuto follows the keys
+	Shade ShadeMode
+	// Layer shows the current layer of the active model instead
+	Layer bool
+}
+
+// ShadeAuto, as the Shade of a Viewport, colors each model as it is shaded
+// in the scene, so the shading keys apply
+const ShadeAuto ShadeMode = -1
+
+// DefaultViewports are the whole scene on the left and the current layer
+// on the right
+func DefaultViewports() []Viewport {
+	return []Viewport{
+		{X: 0, Y: 0, Width: 0.5, Height: 1, Model: -1, Shade: ShadeAuto},
+		{X: 0.5, Y: 0, Width: 0.5, Height: 1, Model: -1, Shade: ShadeAuto, Layer: true},
+	}
+}
+
+// SplitViewports are side by side columns, one for each of models, or for a
+// single model one as the keys shade it and one plain beside it
+func SplitViewports(models int) []Viewport {
+	if models < 2 {
+		return Columns(
+			Viewport{Model: 0, Shade: ShadeAuto},
+			Viewport{Model: 0, Shade: ShadeSolid})
+	}
+	viewports := make([]Viewport, models)
+	for i := range viewports {
+		viewports[i] = Viewport{Model: i, Shade: ShadeAuto}
+	}
+	return Columns(viewports...)
+}
+
+// Columns lays viewports out as equal columns filling the window, left to
+// right
+func Columns(viewports ...Viewport) []Viewport {
+	w := 1 / float64(len(viewports))
+	for i := range viewports {
+		vp := &viewports[i]
+		vp.X, vp.Y, vp.Width, vp.Height = float64(i)*w, 0, w, 1
+	}
+	return viewports
+}
+
+// Rect returns the viewport in pixels of a framebuffer w by h
+func (vp Viewport) Rect(w, h int) (x, y, width, height int32) {
+	x0 := int32(vp.X*float64(w) + 0.5)
+	y0 := int32(vp.Y*float64(h) + 0.5)
+	x1 := int32((vp.X+vp.Width)*float64(w) + 0.5)
+	y1 := int32((vp.Y+vp.Height)*float64(h) + 0.5)
+	return x0, y0, x1 - x0, y1 - y0
+}
+
+// projector is an Interactor that can project its view for any aspect
+// ratio, as viewports of their own shape need
+type projector interface {
+	Project(aspect float64) fauxgl.Matrix
+}
+
+// project returns the interactor's view for a viewport width by height,
+// stretched from the window's if it cannot project for any aspect
+func project(window *glfw.Window, interactor Interactor, width, height int32) fauxgl.Matrix {
+	if p, ok := interactor.(projector); ok && height > 0 {
+		return p.Project(float64(width) / float64(height))
+	}
+	return interactor.Matrix(window)
+}
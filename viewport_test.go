@@ -0,0 +1,41 @@
+package meshview
+
+import "testing"
+
+func TestViewportRect(t *testing.T) {
+	vp := Viewport{X: 0.5, Y: 0, Width: 0.5, Height: 1}
+	x, y, w, h := vp.Rect(1921, 1080)
+	if x != 961 || y != 0 || w != 960 || h != 1080 {
+		t.Errorf("got %d, %d, %d x %d", x, y, w, h)
+	}
+	// neighbors share their edge, leaving no gap or overlap
+	vps := Columns(Viewport{}, Viewport{}, Viewport{})
+	end := int32(0)
+	for i, vp := range vps {
+		x, _, w, _ := vp.Rect(1000, 500)
+		if x != end {
+			t.Errorf("column %d starts at %d, want %d", i, x, end)
+		}
+		end = x + w
+	}
+	if end != 1000 {
+		t.Errorf("columns end at %d, want 1000", end)
+	}
+}
+
+func TestSplitViewports(t *testing.T) {
+	vps := SplitViewports(3)
+	if len(vps) != 3 {
+		t.Fatalf("got %d viewports for 3 models", len(vps))
+	}
+	for i, vp := range vps {
+		if vp.Model != i || vp.Layer || vp.Shade != ShadeAuto {
+			t.Errorf("viewport %d is %+v", i, vp)
+		}
+	}
+	// one model is shown shaded beside plain
+	vps = SplitViewports(1)
+	if len(vps) != 2 || vps[0].Model != 0 || vps[1].Model != 0 || vps[0].Shade == vps[1].Shade {
+		t.Errorf("got %+v for one model", vps)
+	}
+}
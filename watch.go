@@ -0,0 +1,126 @@
+package meshview
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchDebounce is how long a file must go unchanged after a write before
+// it counts as changed, so a file still being written is not read half done
+var WatchDebounce = 300 * time.Millisecond
+
+// WatchPoll is how often files are checked for changes where the system
+// cannot report them
+var WatchPoll = time.Second
+
+// fileState is what a change to a file is noticed by
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func statFile(path string) (fileState, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, false
+	}
+	return fileState{info.Size(), info.ModTime()}, true
+}
+
+// WatchFiles calls fn with each of paths that changes on disk, once writes
+// to it have settled, until ctx is done. It has the system report changes
+// to their directories where it can, so files replaced by renaming are
+// seen too, and otherwise polls them every WatchPoll.
+func WatchFiles(ctx context.Context, paths []string, fn func(path string)) error {
+	// watched maps the absolute path of each file to the path it was given as
+	watched := map[string]string{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		watched[abs] = path
+	}
+	changes := make(chan string, len(watched))
+	stop, err := notifyChanges(watched, changes)
+	if err != nil {
+		log.Println("polling for changes:", err)
+		stop = pollChanges(watched, changes)
+	}
+	defer stop()
+
+	// a file counts as changed once it has been quiet until due and is
+	// still as it was when last written
+	due := map[string]time.Time{}
+	states := map[string]fileState{}
+	for {
+		next := time.Hour
+		now := time.Now()
+		for abs, t := range due {
+			if wait := t.Sub(now); wait > 0 {
+				if wait < next {
+					next = wait
+				}
+				continue
+			}
+			state, ok := statFile(abs)
+			if ok && state != states[abs] {
+				states[abs] = state
+				due[abs] = now.Add(WatchDebounce)
+				if WatchDebounce < next {
+					next = WatchDebounce
+				}
+				continue
+			}
+			delete(due, abs)
+			if ok {
+				fn(watched[abs])
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case abs := <-changes:
+			states[abs], _ = statFile(abs)
+			due[abs] = time.Now().Add(WatchDebounce)
+		case <-time.After(next):
+		}
+	}
+}
+
+// pollChanges sends the absolute path of each watched file on changes when
+// its size or modification time differs from the last look, until stopped
+func pollChanges(watched map[string]string, changes chan<- string) func() {
+	done := make(chan struct{})
+	states := map[string]fileState{}
+	for abs := range watched {
+		states[abs], _ = statFile(abs)
+	}
+	go func() {
+		ticker := time.NewTicker(WatchPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			for abs := range watched {
+				state, _ := statFile(abs)
+				if state == states[abs] {
+					continue
+				}
+				states[abs] = state
+				select {
+				case changes <- abs:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package meshview
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// notifyChanges has inotify report writes to the directories of the
+// watched files, sending the absolute path of each file written on changes
+// until stopped
+func notifyChanges(watched map[string]string, changes chan<- string) (func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_MOVED_TO
+	dirs := map[int32]string{}
+	for abs := range watched {
+		dir := filepath.Dir(abs)
+		wd, err := syscall.InotifyAddWatch(fd, dir, mask)
+		if err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+		dirs[int32(wd)] = dir
+	}
+	// non-blocking, so closing the file ends a read waiting on it
+	file := os.NewFile(uintptr(fd), "inotify")
+	done := make(chan struct{})
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i+syscall.SizeofInotifyEvent <= n; {
+				e := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[i]))
+				name := buf[i+syscall.SizeofInotifyEvent : i+syscall.SizeofInotifyEvent+int(e.Len)]
+				i += syscall.SizeofInotifyEvent + int(e.Len)
+				abs := filepath.Join(dirs[e.Wd], string(bytes.TrimRight(name, "\x00")))
+				if _, ok := watched[abs]; !ok {
+					continue
+				}
+				select {
+				case changes <- abs:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		file.Close()
+	}, nil
+}
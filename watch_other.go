@@ -0,0 +1,10 @@
+//go:build !linux
+
+package meshview
+
+import "errors"
+
+// notifyChanges fails where there is no inotify, leaving files polled
+func notifyChanges(watched map[string]string, changes chan<- string) (func(), error) {
+	return nil, errors.New("change notification not supported")
+}
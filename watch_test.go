@@ -0,0 +1,98 @@
+package meshview
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watchChanges watches paths, returning a channel of the changes reported
+func watchChanges(t *testing.T, paths ...string) <-chan string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ch := make(chan string, 16)
+	go WatchFiles(ctx, paths, func(path string) { ch <- path })
+	// let the watch start before anything is written
+	time.Sleep(50 * time.Millisecond)
+	return ch
+}
+
+func TestWatchFiles(t *testing.T) {
+	defer func(d time.Duration) { WatchDebounce = d }(WatchDebounce)
+	WatchDebounce = 100 * time.Millisecond
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.stl")
+	other := filepath.Join(dir, "other.stl")
+	for _, p := range []string{path, other} {
+		if err := os.WriteFile(p, []byte("solid"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ch := watchChanges(t, path)
+
+	// a write in several parts is one change, reported once it settles
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		file.Write([]byte("facet "))
+		time.Sleep(20 * time.Millisecond)
+	}
+	file.Close()
+	os.WriteFile(other, []byte("solid other"), 0644)
+	select {
+	case got := <-ch:
+		if got != path {
+			t.Errorf("changed %q, want %q", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("unexpected change to %q", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// a file replaced by renaming is seen too
+	tmp := filepath.Join(dir, "model.tmp")
+	os.WriteFile(tmp, []byte("solid new"), 0644)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-ch:
+		if got != path {
+			t.Errorf("changed %q, want %q", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported after rename")
+	}
+}
+
+func TestPollChanges(t *testing.T) {
+	defer func(d time.Duration) { WatchPoll = d }(WatchPoll)
+	WatchPoll = 20 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "model.stl")
+	if err := os.WriteFile(path, []byte("solid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan string, 1)
+	stop := pollChanges(map[string]string{path: path}, changes)
+	defer stop()
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("solid longer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if got != path {
+			t.Errorf("changed %q, want %q", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change polled")
+	}
+}